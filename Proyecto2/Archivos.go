@@ -2,15 +2,17 @@ package main
 
 import (
 	"encoding/binary"
+	"encoding/json"
 	"fmt"
-	"math"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 )
 
-// REMOVE: Elimina un archivo o carpeta en la ruta especificada.
+// REMOVE: Mueve un archivo o carpeta a la papelera /.trash; con -permanent lo elimina definitivamente.
+// En ambos casos una carpeta debe estar vacía.
 func remove(params map[string]string) string {
 	path, hasPath := params["path"]
 	id, hasID := params["id"]
@@ -110,24 +112,8 @@ func remove(params map[string]string) string {
 		return fmt.Sprintf("Error: Permisos insuficientes para eliminar %s", fileName)
 	}
 
-	// Leer bitmaps
-	bitmapInodes := make([]byte, sb.SInodesCount)
-	bitmapBlocks := make([]byte, sb.SBlocksCount)
-	file.Seek(int64(sb.SBmInodeStart), 0)
-	_, err = file.Read(bitmapInodes)
-	if err != nil {
-		return fmt.Sprintf("Error al leer bitmap de inodos: %v", err)
-	}
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	_, err = file.Read(bitmapBlocks)
-	if err != nil {
-		return fmt.Sprintf("Error al leer bitmap de bloques: %v", err)
-	}
-
-	// Liberar recursos
-	freedBlocks := int32(0)
+	// Una carpeta solo se elimina vacía (excepto . y ..), vaya o no a la papelera
 	if targetInode.IType == '0' {
-		// Carpeta: Verificar si está vacía (excepto . y ..)
 		for _, blockIndex := range targetInode.IBlock {
 			if blockIndex == -1 {
 				continue
@@ -142,100 +128,50 @@ func remove(params map[string]string) string {
 					return fmt.Sprintf("Error: La carpeta %s no está vacía", fileName)
 				}
 			}
-			bitmapBlocks[blockIndex] = 0
-			freedBlocks++
-		}
-	} else if targetInode.IType == '1' {
-		// Archivo: Liberar bloques
-		for _, blockIndex := range targetInode.IBlock {
-			if blockIndex == -1 {
-				continue
-			}
-			bitmapBlocks[blockIndex] = 0
-			freedBlocks++
 		}
 	}
 
-	// Liberar inodo
-	bitmapInodes[targetInodeIndex] = 0
+	// Salvo con -permanent o dentro de la propia papelera, el elemento se mueve a /.trash
+	if _, permanent := params["permanent"]; !permanent && pathParts[0] != trashFolder {
+		return moveToTrash(file, mp, sb, parentInodeIndex, fileName, targetInodeIndex, pathParts)
+	}
 
-	// Actualizar carpeta padre
-	folderBlock, err := readFolderBlock(file, sb, targetBlockIndex)
+	alloc, err := newAllocator(file, mp, &sb)
 	if err != nil {
-		return fmt.Sprintf("Error al leer bloque %d: %v", targetBlockIndex, err)
-	}
-	folderBlock.BContent[targetContentIndex] = struct {
-		BName  [12]byte
-		BInode int32
-	}{}
-	file.Seek(int64(sb.SBlockStart+targetBlockIndex*sb.SBlockSize), 0)
-	if err = binary.Write(file, binary.LittleEndian, &folderBlock); err != nil {
-		return fmt.Sprintf("Error al actualizar bloque %d: %v", targetBlockIndex, err)
+		return fmt.Sprintf("Error: %v", err)
 	}
 
-	// Escribir bitmaps
-	file.Seek(int64(sb.SBmInodeStart), 0)
-	if _, err = file.Write(bitmapInodes); err != nil {
-		return fmt.Sprintf("Error al escribir bitmap de inodos: %v", err)
-	}
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	if _, err = file.Write(bitmapBlocks); err != nil {
-		return fmt.Sprintf("Error al escribir bitmap de bloques: %v", err)
+	// Liberar recursos
+	if targetInode.IType == '1' && linkCount(targetInode) > 1 {
+		// Quedan otros enlaces duros: solo se quita esta entrada
+		targetInode.ILinks = linkCount(targetInode) - 1
+		if err := writeInode(file, sb, targetInodeIndex, &targetInode); err != nil {
+			return fmt.Sprintf("Error al escribir inodo %d: %v", targetInodeIndex, err)
+		}
+	} else {
+		// Los enlaces simbólicos se eliminan sin tocar su destino
+		alloc.FreeInodeBlocks(&targetInode)
+
+		// Liberar inodo
+		alloc.FreeInode(targetInodeIndex)
 	}
 
-	// Actualizar superbloque
-	mbr, err := readMBR(file)
+	// Actualizar carpeta padre
+	folderBlock, err := readFolderBlock(file, sb, targetBlockIndex)
 	if err != nil {
-		return fmt.Sprintf("Error al leer MBR: %v", err)
-	}
-	var partStart int32
-	foundPart := false
-	for _, p := range mbr.MbrPartitions {
-		name := strings.Trim(string(p.PartName[:]), "\x00")
-		if name == mp.Name {
-			partStart = p.PartStart
-			foundPart = true
-			break
-		}
+		return fmt.Sprintf("Error al leer bloque %d: %v", targetBlockIndex, err)
 	}
-	if !foundPart {
-		for _, p := range mbr.MbrPartitions {
-			if p.PartType == 'E' {
-				currentPos := p.PartStart
-				for currentPos != -1 {
-					file.Seek(int64(currentPos), 0)
-					var ebr EBR
-					if err = binary.Read(file, binary.LittleEndian, &ebr); err != nil {
-						continue
-					}
-					name := strings.Trim(string(ebr.PartName[:]), "\x00")
-					if name == mp.Name && ebr.PartSize > 0 {
-						partStart = ebr.PartStart
-						foundPart = true
-						break
-					}
-					currentPos = ebr.PartNext
-				}
-			}
-			if foundPart {
-				break
-			}
-		}
+	folderBlock.BContent[targetContentIndex] = FolderContent{BInode: -1}
+	if err = writeFolderBlock(file, sb, targetBlockIndex, &folderBlock); err != nil {
+		return fmt.Sprintf("Error al actualizar bloque %d: %v", targetBlockIndex, err)
 	}
-	if !foundPart {
-		return fmt.Sprintf("Error: Partición %s no encontrada en MBR ni EBR", mp.Name)
+	if err = touchInode(file, sb, parentInodeIndex, false, true); err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 
-	file.Seek(int64(partStart), 0)
-	var sbUpdated Superblock
-	if err = binary.Read(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Sprintf("Error al leer superbloque: %v", err)
-	}
-	sbUpdated.SFreeInodesCount++
-	sbUpdated.SFreeBlocksCount += freedBlocks
-	file.Seek(int64(partStart), 0)
-	if err = binary.Write(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Sprintf("Error al escribir superbloque: %v", err)
+	// Escribir bitmaps y superbloque
+	if err = alloc.Commit(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 	if err = file.Sync(); err != nil {
 		return fmt.Sprintf("Error syncing disk: %v", err)
@@ -244,164 +180,29 @@ func remove(params map[string]string) string {
 	return fmt.Sprintf("%s eliminado exitosamente", fileName)
 }
 
-// COPY: Copia un archivo o carpeta a una nueva ubicación.
-func copyMap(params map[string]string) string {
-	src, hasSrc := params["path"]
-	dest, hasDest := params["dest"]
-	id, hasID := params["id"]
-	if !hasSrc || !hasDest || !hasID {
-		return "Error: Parámetros -path, -dest y -id son obligatorios"
-	}
-
-	if currentSession == nil {
-		return "Error: No hay sesión activa"
-	}
-
-	// Verificar partición montada
-	var mp *MountedPartition
-	for _, p := range mountedPartitions {
-		if p.ID == id {
-			mp = &p
-			break
-		}
-	}
-	if mp == nil {
-		return fmt.Sprintf("Error: Partición %s no encontrada", id)
-	}
-
-	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
-	if err != nil {
-		return fmt.Sprintf("Error al abrir disco: %v", err)
-	}
-	defer file.Close()
-
-	sb, err := readSuperblock(file, mp)
-	if err != nil {
-		return fmt.Sprintf("Error al leer superbloque: %v", err)
-	}
-
-	// Procesar rutas
-	srcParts, err := normalizePath(src)
-	if err != nil {
-		return fmt.Sprintf("Error en ruta fuente: %v", err)
-	}
-	destParts, err := normalizePath(dest)
-	if err != nil {
-		return fmt.Sprintf("Error en ruta destino: %v", err)
-	}
-
-	// Navegar a la carpeta padre del origen
-	srcFileName := srcParts[len(srcParts)-1]
-	srcParentPath := srcParts[:len(srcParts)-1]
-	srcParentInode, err := navigateToParent(file, sb, srcParentPath)
-	if err != nil {
-		return fmt.Sprintf("Error al navegar a la carpeta padre del origen: %v", err)
-	}
-
-	// Encontrar el inodo del origen
-	var srcInodeIndex int32 = -1
-	for _, blockIndex := range (func() Inode {
-		inode, _ := readInode(file, sb, srcParentInode)
-		return inode
-	})().IBlock {
-		if blockIndex == -1 {
-			continue
-		}
-		folderBlock, err := readFolderBlock(file, sb, blockIndex)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bloque %d: %v", blockIndex, err)
-		}
-		for _, content := range folderBlock.BContent {
-			name := strings.Trim(string(content.BName[:]), "\x00")
-			if name == srcFileName {
-				srcInodeIndex = content.BInode
-				break
-			}
-		}
-		if srcInodeIndex != -1 {
-			break
-		}
-	}
-	if srcInodeIndex == -1 {
-		return fmt.Sprintf("Error: %s no encontrado", srcFileName)
-	}
-
-	srcInode, err := readInode(file, sb, srcInodeIndex)
-	if err != nil {
-		return fmt.Sprintf("Error al leer inodo %d: %v", srcInodeIndex, err)
-	}
-
-	// Navegar a la carpeta destino
-	destParentInode, err := navigateToParent(file, sb, destParts[:len(destParts)-1])
-	if err != nil {
-		return fmt.Sprintf("Error al navegar a la carpeta destino: %v", err)
+// MOVE: Mueve un archivo o carpeta a una nueva ubicación, también a otra partición con -srcid y -destid.
+func move(params map[string]string) string {
+	// Entre particiones distintas se copia el subárbol y después se elimina el origen
+	srcID, hasSrcID := params["srcid"]
+	if !hasSrcID {
+		srcID = params["id"]
 	}
-
-	// Verificar permisos
-	if !hasWritePermission(srcInode, currentSession.UserID, currentSession.GroupID) {
-		return fmt.Sprintf("Error: Permisos insuficientes para leer %s", srcFileName)
+	destID, hasDestID := params["destid"]
+	if !hasDestID {
+		destID = params["id"]
 	}
-	destParentInodeData, err := readInode(file, sb, destParentInode)
-	if err != nil {
-		return fmt.Sprintf("Error al leer inodo destino: %v", err)
+	if srcID != destID {
+		return transfer(params, true)
 	}
-	if !hasWritePermission(destParentInodeData, currentSession.UserID, currentSession.GroupID) {
-		return "Error: Permisos insuficientes para escribir en la carpeta destino"
+	if srcID != "" {
+		params["id"] = srcID
 	}
 
-	// Verificar si el destino ya existe
-	destFileName := destParts[len(destParts)-1]
-	for _, blockIndex := range destParentInodeData.IBlock {
-		if blockIndex == -1 {
-			continue
-		}
-		folderBlock, err := readFolderBlock(file, sb, blockIndex)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bloque %d: %v", blockIndex, err)
-		}
-		for _, content := range folderBlock.BContent {
-			name := strings.Trim(string(content.BName[:]), "\x00")
-			if name == destFileName {
-				return fmt.Sprintf("Error: %s ya existe en la ruta destino", destFileName)
-			}
-		}
-	}
-
-	// Copiar archivo
-	if srcInode.IType == '1' {
-		// Leer contenido
-		var content strings.Builder
-		for _, blockIndex := range srcInode.IBlock {
-			if blockIndex == -1 {
-				continue
-			}
-			fileBlock, err := readFileBlock(file, sb, blockIndex)
-			if err != nil {
-				return fmt.Sprintf("Error al leer bloque %d: %v", blockIndex, err)
-			}
-			content.Write(fileBlock.BContent[:])
-		}
-		contentStr := strings.Trim(content.String(), "\x00")
-
-		// Crear nuevo archivo en destino
-		newParams := map[string]string{
-			"path": dest,
-			"cont": contentStr,
-			"id":   id,
-		}
-		return mkfile(newParams)
-	}
-
-	return "Error: Copia de carpetas no implementada"
-}
-
-// MOVE: Mueve un archivo o carpeta a una nueva ubicación.
-func move(params map[string]string) string {
 	src, hasSrc := params["path"]
 	dest, hasDest := params["dest"]
 	id, hasID := params["id"]
 	if !hasSrc || !hasDest || !hasID {
-		return "Error: Parámetros -path, -dest y -id son obligatorios"
+		return "Error: Parámetros -path, -dest y -id (o -srcid y -destid) son obligatorios"
 	}
 
 	if currentSession == nil {
@@ -440,6 +241,10 @@ func move(params map[string]string) string {
 	if err != nil {
 		return fmt.Sprintf("Error en ruta destino: %v", err)
 	}
+	srcPath := "/" + strings.Join(srcParts, "/")
+	if destPath := "/" + strings.Join(destParts, "/"); destPath == srcPath || strings.HasPrefix(destPath, srcPath+"/") {
+		return fmt.Sprintf("Error: No se puede mover %s dentro de sí mismo", srcPath)
+	}
 
 	// Navegar a la carpeta padre del origen
 	srcFileName := srcParts[len(srcParts)-1]
@@ -522,145 +327,38 @@ func move(params map[string]string) string {
 		}
 	}
 
+	// Añadir a la carpeta destino antes de quitar el origen, para no perder el elemento si falla
+	alloc, err := newAllocator(file, mp, &sb)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = addFolderEntry(file, sb, alloc, destParentInode, destFileName, srcInodeIndex); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = setParentEntry(file, sb, srcInodeIndex, destParentInode); err != nil {
+		removeFolderEntry(file, sb, destParentInode, destFileName)
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
+	}
+
 	// Actualizar carpeta padre origen
 	folderBlock, err := readFolderBlock(file, sb, srcBlockIndex)
+	if err == nil {
+		folderBlock.BContent[srcContentIndex] = FolderContent{BInode: -1}
+		err = writeFolderBlock(file, sb, srcBlockIndex, &folderBlock)
+	}
 	if err != nil {
-		return fmt.Sprintf("Error al leer bloque %d: %v", srcBlockIndex, err)
-	}
-	folderBlock.BContent[srcContentIndex] = struct {
-		BName  [12]byte
-		BInode int32
-	}{}
-	file.Seek(int64(sb.SBlockStart+srcBlockIndex*sb.SBlockSize), 0)
-	if err = binary.Write(file, binary.LittleEndian, &folderBlock); err != nil {
+		setParentEntry(file, sb, srcInodeIndex, srcParentInode)
+		removeFolderEntry(file, sb, destParentInode, destFileName)
+		alloc.Rollback()
 		return fmt.Sprintf("Error al actualizar bloque %d: %v", srcBlockIndex, err)
 	}
-
-	// Añadir a la carpeta destino
-	found := false
-	for _, blockIndex := range destParentInodeData.IBlock {
-		if blockIndex == -1 {
-			continue
-		}
-		folderBlock, err = readFolderBlock(file, sb, blockIndex)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bloque %d: %v", blockIndex, err)
-		}
-		for i := range folderBlock.BContent {
-			name := strings.Trim(string(folderBlock.BContent[i].BName[:]), "\x00")
-			if name == "" {
-				copy(folderBlock.BContent[i].BName[:], []byte(destFileName))
-				folderBlock.BContent[i].BInode = srcInodeIndex
-				file.Seek(int64(sb.SBlockStart+blockIndex*sb.SBlockSize), 0)
-				if err = binary.Write(file, binary.LittleEndian, &folderBlock); err != nil {
-					return fmt.Sprintf("Error al actualizar bloque %d: %v", blockIndex, err)
-				}
-				found = true
-				break
-			}
-		}
-		if found {
-			break
-		}
+	if err = alloc.Commit(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
-
-	if !found {
-		// Asignar nuevo bloque a la carpeta destino
-		bitmapBlocks := make([]byte, sb.SBlocksCount)
-		file.Seek(int64(sb.SBmBlockStart), 0)
-		_, err = file.Read(bitmapBlocks)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bitmap de bloques: %v", err)
-		}
-		currentBlock := int32(2)
-		for currentBlock < sb.SBlocksCount && bitmapBlocks[currentBlock] == 1 {
-			currentBlock++
-		}
-		if currentBlock >= sb.SBlocksCount {
-			return "Error: No hay bloques libres para la carpeta destino"
-		}
-		newBlockIndex := currentBlock
-		bitmapBlocks[currentBlock] = 1
-
-		var newFolderBlock FolderBlock
-		copy(newFolderBlock.BContent[0].BName[:], []byte(destFileName))
-		newFolderBlock.BContent[0].BInode = srcInodeIndex
-		file.Seek(int64(sb.SBlockStart+newBlockIndex*sb.SBlockSize), 0)
-		if err = binary.Write(file, binary.LittleEndian, &newFolderBlock); err != nil {
-			return fmt.Sprintf("Error al escribir bloque %d: %v", newBlockIndex, err)
-		}
-
-		// Actualizar inodo destino
-		for i := range destParentInodeData.IBlock {
-			if destParentInodeData.IBlock[i] == -1 {
-				destParentInodeData.IBlock[i] = newBlockIndex
-				break
-			}
-		}
-		file.Seek(int64(sb.SInodeStart+destParentInode*sb.SInodeSize), 0)
-		if err = binary.Write(file, binary.LittleEndian, &destParentInodeData); err != nil {
-			return fmt.Sprintf("Error al actualizar inodo %d: %v", destParentInode, err)
-		}
-
-		// Escribir bitmap
-		file.Seek(int64(sb.SBmBlockStart), 0)
-		if _, err = file.Write(bitmapBlocks); err != nil {
-			return fmt.Sprintf("Error al escribir bitmap de bloques: %v", err)
-		}
-
-		// Actualizar superbloque
-		mbr, err := readMBR(file)
-		if err != nil {
-			return fmt.Sprintf("Error al leer MBR: %v", err)
-		}
-		var partStart int32
-		foundPart := false
-		for _, p := range mbr.MbrPartitions {
-			name := strings.Trim(string(p.PartName[:]), "\x00")
-			if name == mp.Name {
-				partStart = p.PartStart
-				foundPart = true
-				break
-			}
-		}
-		if !foundPart {
-			for _, p := range mbr.MbrPartitions {
-				if p.PartType == 'E' {
-					currentPos := p.PartStart
-					for currentPos != -1 {
-						file.Seek(int64(currentPos), 0)
-						var ebr EBR
-						if err = binary.Read(file, binary.LittleEndian, &ebr); err != nil {
-							continue
-						}
-						name := strings.Trim(string(ebr.PartName[:]), "\x00")
-						if name == mp.Name && ebr.PartSize > 0 {
-							partStart = ebr.PartStart
-							foundPart = true
-							break
-						}
-						currentPos = ebr.PartNext
-					}
-				}
-				if foundPart {
-					break
-				}
-			}
-		}
-		if !foundPart {
-			return fmt.Sprintf("Error: Partición %s no encontrada en MBR ni EBR", mp.Name)
-		}
-
-		file.Seek(int64(partStart), 0)
-		var sbUpdated Superblock
-		if err = binary.Read(file, binary.LittleEndian, &sbUpdated); err != nil {
-			return fmt.Sprintf("Error al leer superbloque: %v", err)
-		}
-		sbUpdated.SFreeBlocksCount--
-		file.Seek(int64(partStart), 0)
-		if err = binary.Write(file, binary.LittleEndian, &sbUpdated); err != nil {
-			return fmt.Sprintf("Error al escribir superbloque: %v", err)
-		}
+	if err = touchInode(file, sb, srcParentInode, false, true); err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 
 	if err = file.Sync(); err != nil {
@@ -670,7 +368,10 @@ func move(params map[string]string) string {
 	return fmt.Sprintf("%s movido exitosamente a %s", srcFileName, dest)
 }
 
-// FIND: Busca archivos o carpetas que coincidan con un patrón.
+// FIND: Busca archivos o carpetas cuyo nombre coincida con un patrón con comodines * y ?,
+// con filtros opcionales por tipo, tamaño, dueño, permisos y fecha de modificación. El
+// patrón debe coincidir con el nombre completo: para buscar un texto dentro del nombre
+// use -name="*texto*".
 func find(params map[string]string) string {
 	path, hasPath := params["path"]
 	id, hasID := params["id"]
@@ -678,6 +379,14 @@ func find(params map[string]string) string {
 	if !hasPath || !hasID || !hasName {
 		return "Error: Parámetros -path, -id y -name son obligatorios"
 	}
+	filter := findFilter{pattern: strings.Trim(name, "\""), size: -1, uid: -1, perm: -1}
+	if _, err := filepath.Match(filter.pattern, ""); err != nil {
+		return fmt.Sprintf("Error: Patrón -name no válido: %s", filter.pattern)
+	}
+	output := strings.ToLower(params["output"])
+	if output != "" && output != "list" && output != "tree" && output != "json" {
+		return fmt.Sprintf("Error: Valor de -output no válido: %s (use list, tree o json)", params["output"])
+	}
 
 	if currentSession == nil {
 		return "Error: No hay sesión activa"
@@ -706,8 +415,17 @@ func find(params map[string]string) string {
 		return fmt.Sprintf("Error al leer superbloque: %v", err)
 	}
 
+	usersContent, err := readUsersTxt(file, sb)
+	if err != nil {
+		return fmt.Sprintf("Error al leer users.txt: %v", err)
+	}
+	users, _ := userAndGroupNames(usersContent)
+	if err := filter.parse(params, users); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
 	// Procesar la ruta
-	pathParts, err := normalizePath(path)
+	pathParts, err := splitPath(path)
 	if err != nil {
 		return fmt.Sprintf("Error: %v", err)
 	}
@@ -717,23 +435,184 @@ func find(params map[string]string) string {
 	if err != nil {
 		return fmt.Sprintf("Error al navegar a %s: %v", path, err)
 	}
+	startInode, err := readInode(file, sb, currentInode)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", currentInode, err)
+	}
+	if startInode.IType != '0' {
+		return fmt.Sprintf("Error: %s no es una carpeta", path)
+	}
+	if !hasReadPermission(startInode, currentSession) {
+		return fmt.Sprintf("Error: Permiso denegado para leer %s", path)
+	}
 
 	// Buscar recursivamente
-	var results []string
-	err = findRecursive(file, sb, currentInode, name, path, &results)
+	root := strings.TrimSuffix("/"+strings.Join(pathParts, "/"), "/")
+	var results []findResult
+	err = findRecursive(file, sb, currentInode, &filter, root, &results, map[int32]bool{})
 	if err != nil {
 		return fmt.Sprintf("Error durante la búsqueda: %v", err)
 	}
 
+	switch output {
+	case "json":
+		items := make([]map[string]interface{}, 0, len(results))
+		for _, r := range results {
+			item := map[string]interface{}{
+				"path":          r.path,
+				"name":          filepath.Base(r.path),
+				"type":          string(r.inode.IType),
+				"size":          r.inode.ISize,
+				"owner":         users[r.inode.IUid],
+				"permissions":   fmt.Sprintf("%03d", r.inode.IPerm),
+				"modified_date": strings.Trim(string(r.inode.IMtime[:]), "\x00"),
+			}
+			if r.target != "" {
+				item["target"] = r.target
+			}
+			items = append(items, item)
+		}
+		result, err := json.Marshal(items)
+		if err != nil {
+			return fmt.Sprintf("Error al generar JSON: %v", err)
+		}
+		return string(result)
+	}
+
 	if len(results) == 0 {
-		return fmt.Sprintf("No se encontraron coincidencias para %s", name)
+		return fmt.Sprintf("No se encontraron coincidencias para %s", filter.pattern)
+	}
+	if output == "tree" {
+		return findTree(root, results)
 	}
+	lines := make([]string, len(results))
+	for i, r := range results {
+		lines[i] = r.path
+		if r.target != "" {
+			lines[i] += " -> " + r.target
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// findFilter reúne los criterios de find; los campos en -1 o vacíos no filtran
+type findFilter struct {
+	pattern  string
+	itype    byte
+	sizeCmp  int // -1 menor que, 0 igual a, 1 mayor que size
+	size     int64
+	uid      int32
+	perm     int32
+	newer    time.Time
+	hasNewer bool
+}
+
+// findResult es un elemento encontrado por find
+type findResult struct {
+	path   string
+	inode  Inode
+	target string
+}
 
-	return strings.Join(results, "\n")
+// parse lee -type, -size, -user, -perm y -newer
+func (f *findFilter) parse(params map[string]string, users map[int32]string) error {
+	if value, ok := params["type"]; ok {
+		switch strings.ToLower(value) {
+		case "f":
+			f.itype = '1'
+		case "d":
+			f.itype = '0'
+		case "l":
+			f.itype = '2'
+		default:
+			return fmt.Errorf("valor de -type no válido: %s (use f, d o l)", value)
+		}
+	}
+	if value, ok := params["size"]; ok {
+		num := value
+		switch {
+		case strings.HasPrefix(num, "+"):
+			f.sizeCmp, num = 1, num[1:]
+		case strings.HasPrefix(num, "-"):
+			f.sizeCmp, num = -1, num[1:]
+		}
+		if num == "" {
+			return fmt.Errorf("valor de -size no válido: %s (ejemplos: 100, +1k, -2m)", value)
+		}
+		var err error
+		if unit := strings.ToUpper(num[len(num)-1:]); unit == "K" || unit == "M" {
+			f.size, err = parseSize(num[:len(num)-1], unit)
+		} else {
+			f.size, err = strconv.ParseInt(strings.TrimSuffix(strings.ToUpper(num), "B"), 10, 64)
+		}
+		if err != nil || f.size < 0 {
+			return fmt.Errorf("valor de -size no válido: %s (ejemplos: 100, +1k, -2m)", value)
+		}
+	}
+	if value, ok := params["user"]; ok {
+		for uid, name := range users {
+			if name == value {
+				f.uid = uid
+			}
+		}
+		if f.uid == -1 {
+			return fmt.Errorf("el usuario %s no existe", value)
+		}
+	}
+	if value, ok := params["perm"]; ok {
+		perm, err := strconv.Atoi(value)
+		if err != nil || len(value) != 3 || strings.ContainsAny(value, "89") {
+			return fmt.Errorf("valor de -perm no válido: %s (ejemplo: 664)", value)
+		}
+		f.perm = int32(perm)
+	}
+	if value, ok := params["newer"]; ok {
+		value = strings.Trim(value, "\"")
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", value, time.Local)
+		if err != nil {
+			t, err = time.ParseInLocation("2006-01-02", value, time.Local)
+		}
+		if err != nil {
+			return fmt.Errorf("valor de -newer no válido: %s (use AAAA-MM-DD o \"AAAA-MM-DD HH:MM:SS\")", value)
+		}
+		f.newer, f.hasNewer = t, true
+	}
+	return nil
+}
+
+// matches indica si un elemento llamado name con el inodo dado cumple todos los criterios
+func (f *findFilter) matches(name string, inode Inode) bool {
+	if ok, _ := filepath.Match(f.pattern, name); !ok {
+		return false
+	}
+	if f.itype != 0 && inode.IType != f.itype {
+		return false
+	}
+	if f.size >= 0 {
+		size := int64(inode.ISize)
+		if (f.sizeCmp > 0 && size <= f.size) || (f.sizeCmp < 0 && size >= f.size) || (f.sizeCmp == 0 && size != f.size) {
+			return false
+		}
+	}
+	if f.uid != -1 && inode.IUid != f.uid {
+		return false
+	}
+	if f.perm != -1 && inode.IPerm != f.perm {
+		return false
+	}
+	if f.hasNewer {
+		mtime, ok := inodeTime(inode.IMtime)
+		if !ok || !mtime.After(f.newer) {
+			return false
+		}
+	}
+	return true
 }
 
 // findRecursive: Función auxiliar para buscar recursivamente.
-func findRecursive(file *os.File, sb Superblock, inodeIndex int32, pattern, currentPath string, results *[]string) error {
+// Los enlaces simbólicos no se siguen, no se entra a carpetas sin permiso de lectura
+// y visited evita recorrer dos veces una carpeta.
+func findRecursive(file *os.File, sb Superblock, inodeIndex int32, filter *findFilter, currentPath string, results *[]findResult, visited map[int32]bool) error {
 	inode, err := readInode(file, sb, inodeIndex)
 	if err != nil {
 		return fmt.Errorf("error al leer inodo %d: %v", inodeIndex, err)
@@ -742,6 +621,10 @@ func findRecursive(file *os.File, sb Superblock, inodeIndex int32, pattern, curr
 	if inode.IType != '0' {
 		return nil // No es una carpeta, ignorar
 	}
+	if visited[inodeIndex] {
+		return fmt.Errorf("ciclo detectado en %s (inodo %d)", currentPath, inodeIndex)
+	}
+	visited[inodeIndex] = true
 
 	for _, blockIndex := range inode.IBlock {
 		if blockIndex == -1 {
@@ -756,16 +639,21 @@ func findRecursive(file *os.File, sb Superblock, inodeIndex int32, pattern, curr
 			if name == "" || name == "." || name == ".." {
 				continue
 			}
-			if strings.Contains(name, pattern) {
-				*results = append(*results, fmt.Sprintf("%s/%s", currentPath, name))
-			}
 			if content.BInode != -1 {
 				inodeChild, err := readInode(file, sb, content.BInode)
 				if err != nil {
 					continue
 				}
-				if inodeChild.IType == '0' {
-					err = findRecursive(file, sb, content.BInode, pattern, fmt.Sprintf("%s/%s", currentPath, name), results)
+				childPath := fmt.Sprintf("%s/%s", currentPath, name)
+				if filter.matches(name, inodeChild) {
+					result := findResult{path: childPath, inode: inodeChild}
+					if inodeChild.IType == '2' {
+						result.target, _ = readSymlinkTarget(file, sb, inodeChild)
+					}
+					*results = append(*results, result)
+				}
+				if inodeChild.IType == '0' && hasReadPermission(inodeChild, currentSession) {
+					err = findRecursive(file, sb, content.BInode, filter, childPath, results, visited)
 					if err != nil {
 						return err
 					}
@@ -776,6 +664,54 @@ func findRecursive(file *os.File, sb Superblock, inodeIndex int32, pattern, curr
 	return nil
 }
 
+// findTree dibuja los resultados como un árbol bajo root, incluyendo las carpetas
+// intermedias necesarias para ubicar cada coincidencia
+func findTree(root string, results []findResult) string {
+	type node struct {
+		children map[string]*node
+		order    []string
+		label    string
+	}
+	top := &node{children: map[string]*node{}}
+	for _, r := range results {
+		current := top
+		rel := strings.TrimPrefix(r.path, root+"/")
+		parts := strings.Split(rel, "/")
+		for i, part := range parts {
+			child, ok := current.children[part]
+			if !ok {
+				child = &node{children: map[string]*node{}, label: part}
+				current.children[part] = child
+				current.order = append(current.order, part)
+			}
+			if i == len(parts)-1 && r.target != "" {
+				child.label = part + " -> " + r.target
+			}
+			current = child
+		}
+	}
+
+	var salida strings.Builder
+	if root == "" {
+		root = "/"
+	}
+	salida.WriteString(root)
+	var draw func(n *node, prefix string)
+	draw = func(n *node, prefix string) {
+		for i, name := range n.order {
+			child := n.children[name]
+			branch, next := "├── ", "│   "
+			if i == len(n.order)-1 {
+				branch, next = "└── ", "    "
+			}
+			salida.WriteString("\n" + prefix + branch + child.label)
+			draw(child, prefix+next)
+		}
+	}
+	draw(top, "")
+	return salida.String()
+}
+
 // CHOWN: Cambia el propietario de un archivo o carpeta.
 func chown(params map[string]string) string {
 	path, hasPath := params["path"]
@@ -899,8 +835,7 @@ func changeOwner(file *os.File, sb Superblock, inodeIndex int32, newUID int32, r
 	}
 
 	inode.IUid = newUID
-	file.Seek(int64(sb.SInodeStart+inodeIndex*sb.SInodeSize), 0)
-	if err = binary.Write(file, binary.LittleEndian, &inode); err != nil {
+	if err = writeInode(file, sb, inodeIndex, &inode); err != nil {
 		return fmt.Errorf("error al escribir inodo %d: %v", inodeIndex, err)
 	}
 
@@ -1038,8 +973,7 @@ func changePermissions(file *os.File, sb Superblock, inodeIndex int32, newPerm i
 	}
 
 	inode.IPerm = newPerm
-	file.Seek(int64(sb.SInodeStart+inodeIndex*sb.SInodeSize), 0)
-	if err = binary.Write(file, binary.LittleEndian, &inode); err != nil {
+	if err = writeInode(file, sb, inodeIndex, &inode); err != nil {
 		return fmt.Errorf("error al escribir inodo %d: %v", inodeIndex, err)
 	}
 
@@ -1073,9 +1007,58 @@ func changePermissions(file *os.File, sb Superblock, inodeIndex int32, newPerm i
 func edit(params map[string]string) string {
 	path, hasPath := params["path"]
 	id, hasID := params["id"]
-	cont, hasCont := params["cont"]
-	if !hasPath || !hasID || !hasCont {
-		return "Error: Parámetros -path, -id y -cont son obligatorios"
+	if !hasPath || !hasID {
+		return "Error: Parámetros -path e -id son obligatorios"
+	}
+
+	// Modo de edición: sin modificador -text reemplaza todo el contenido. Igual que en mkfile,
+	// -text es el contenido literal; -cont (archivo del anfitrión) no aplica a edit.
+	if _, hasCont := params["cont"]; hasCont {
+		return "Error: edit recibe el contenido con -text"
+	}
+	text, hasText := params["text"]
+	_, hasAppend := params["append"]
+	offsetStr, hasOffset := params["offset"]
+	truncateStr, hasTruncate := params["truncate"]
+	oldText, hasReplace := params["replace"]
+	newText, hasWith := params["with"]
+	modes := 0
+	for _, has := range []bool{hasAppend, hasOffset, hasTruncate, hasReplace} {
+		if has {
+			modes++
+		}
+	}
+	if modes > 1 {
+		return "Error: Solo se puede especificar uno de -append, -offset, -truncate y -replace"
+	}
+	if (hasTruncate || hasReplace) && hasText {
+		return "Error: -text no se usa con -truncate ni -replace"
+	}
+	if !hasTruncate && !hasReplace && !hasText {
+		return "Error: Parámetro -text es obligatorio"
+	}
+	if hasReplace {
+		if oldText == "" || !hasWith {
+			return "Error: -replace requiere un texto no vacío y -with"
+		}
+		if strings.Contains(oldText, "\n") || strings.Contains(newText, "\n") {
+			return "Error: -replace y -with trabajan por línea y no pueden contener saltos de línea"
+		}
+	}
+	var position int
+	for _, arg := range []struct {
+		has   bool
+		name  string
+		value string
+	}{{hasOffset, "offset", offsetStr}, {hasTruncate, "truncate", truncateStr}} {
+		if !arg.has {
+			continue
+		}
+		n, err := strconv.Atoi(arg.value)
+		if err != nil || n < 0 {
+			return fmt.Sprintf("Error: Valor de -%s inválido: %s", arg.name, arg.value)
+		}
+		position = n
 	}
 
 	if currentSession == nil {
@@ -1158,136 +1141,82 @@ func edit(params map[string]string) string {
 		return fmt.Sprintf("Error: Permisos insuficientes para editar %s", fileName)
 	}
 
-	// Leer bitmaps
-	bitmapBlocks := make([]byte, sb.SBlocksCount)
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	_, err = file.Read(bitmapBlocks)
-	if err != nil {
-		return fmt.Sprintf("Error al leer bitmap de bloques: %v", err)
-	}
-
-	// Liberar bloques anteriores
-	freedBlocks := int32(0)
-	for i, blockIndex := range inode.IBlock {
-		if blockIndex != -1 {
-			bitmapBlocks[blockIndex] = 0
-			inode.IBlock[i] = -1
-			freedBlocks++
+	// Una posición fuera del tamaño máximo se rechaza antes de reservar memoria para el contenido
+	if hasOffset || hasTruncate {
+		err = checkFileSize(sb, position)
+		if err == nil && hasOffset {
+			err = checkFileSize(sb, position+len(text))
 		}
-	}
-
-	// Asignar nuevos bloques
-	content := []byte(cont)
-	numBlocks := int32(math.Ceil(float64(len(content)) / 64.0))
-	if numBlocks > 15 {
-		return fmt.Sprintf("Error: El contenido requiere %d bloques, máximo 15", numBlocks)
-	}
-
-	newBlocks := make([]int32, numBlocks)
-	currentBlock := int32(2)
-	for i := int32(0); i < numBlocks; i++ {
-		for currentBlock < sb.SBlocksCount && bitmapBlocks[currentBlock] == 1 {
-			currentBlock++
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+	}
+
+	// Calcular el contenido nuevo a partir del actual
+	current, err := readFileContent(file, sb, inode)
+	if err != nil {
+		return fmt.Sprintf("Error al leer %s: %v", fileName, err)
+	}
+	var content []byte
+	detalle := ""
+	switch {
+	case hasAppend:
+		content = append(current, text...)
+	case hasOffset:
+		// Igual que pwrite, escribir más allá del final rellena el hueco con bytes nulos
+		end := max(len(current), position+len(text))
+		content = make([]byte, end)
+		copy(content, current)
+		copy(content[position:], text)
+	case hasTruncate:
+		content = make([]byte, position)
+		copy(content, current)
+	case hasReplace:
+		lines := strings.Split(string(current), "\n")
+		changed := 0
+		for k, line := range lines {
+			if strings.Contains(line, oldText) {
+				lines[k] = strings.ReplaceAll(line, oldText, newText)
+				changed++
+			}
 		}
-		if currentBlock >= sb.SBlocksCount {
-			return "Error: No hay bloques libres"
+		if changed == 0 {
+			return fmt.Sprintf("Error: %s no contiene %s", fileName, oldText)
 		}
-		newBlocks[i] = currentBlock
-		bitmapBlocks[currentBlock] = 1
-		currentBlock++
+		content = []byte(strings.Join(lines, "\n"))
+		detalle = fmt.Sprintf(", %d línea(s) modificada(s)", changed)
+	default:
+		content = []byte(text)
 	}
 
-	// Escribir nuevos bloques
-	for i, blockIndex := range newBlocks {
-		var block FileBlock
-		start := int32(i * 64)
-		end := start + 64
-		if end > int32(len(content)) {
-			end = int32(len(content))
-		}
-		copy(block.BContent[:], content[start:end])
-		file.Seek(int64(sb.SBlockStart+blockIndex*sb.SBlockSize), 0)
-		if err = binary.Write(file, binary.LittleEndian, &block); err != nil {
-			return fmt.Sprintf("Error al escribir bloque %d: %v", blockIndex, err)
-		}
+	// Reutilizar los bloques del archivo, asignando o liberando según el nuevo tamaño
+	alloc, err := newAllocator(file, mp, &sb)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = resizeFileContent(file, sb, alloc, &inode, content); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
 	}
 
 	// Actualizar inodo
-	inode.ISize = int32(len(content))
-	for i, blockIndex := range newBlocks {
-		inode.IBlock[i] = blockIndex
-	}
-	fecha := time.Now().Format("2006-01-02 15:04:05")
-	copy(inode.IMtime[:], []byte(fecha))
-	file.Seek(int64(sb.SInodeStart+targetInodeIndex*sb.SInodeSize), 0)
-	if err = binary.Write(file, binary.LittleEndian, &inode); err != nil {
+	inode.IMtime = timestamp()
+	if err = writeInode(file, sb, targetInodeIndex, &inode); err != nil {
 		return fmt.Sprintf("Error al escribir inodo %d: %v", targetInodeIndex, err)
 	}
-
-	// Escribir bitmap
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	if _, err = file.Write(bitmapBlocks); err != nil {
-		return fmt.Sprintf("Error al escribir bitmap de bloques: %v", err)
-	}
-
-	// Actualizar superbloque
-	mbr, err := readMBR(file)
-	if err != nil {
-		return fmt.Sprintf("Error al leer MBR: %v", err)
-	}
-	var partStart int32
-	foundPart := false
-	for _, p := range mbr.MbrPartitions {
-		name := strings.Trim(string(p.PartName[:]), "\x00")
-		if name == mp.Name {
-			partStart = p.PartStart
-			foundPart = true
-			break
-		}
-	}
-	if !foundPart {
-		for _, p := range mbr.MbrPartitions {
-			if p.PartType == 'E' {
-				currentPos := p.PartStart
-				for currentPos != -1 {
-					file.Seek(int64(currentPos), 0)
-					var ebr EBR
-					if err = binary.Read(file, binary.LittleEndian, &ebr); err != nil {
-						continue
-					}
-					name := strings.Trim(string(ebr.PartName[:]), "\x00")
-					if name == mp.Name && ebr.PartSize > 0 {
-						partStart = ebr.PartStart
-						foundPart = true
-						break
-					}
-					currentPos = ebr.PartNext
-				}
-			}
-			if foundPart {
-				break
-			}
-		}
-	}
-	if !foundPart {
-		return fmt.Sprintf("Error: Partición %s no encontrada en MBR ni EBR", mp.Name)
+	if err = touchInode(file, sb, parentInode, false, true); err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 
-	file.Seek(int64(partStart), 0)
-	var sbUpdated Superblock
-	if err = binary.Read(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Sprintf("Error al leer superbloque: %v", err)
-	}
-	sbUpdated.SFreeBlocksCount += freedBlocks - numBlocks
-	file.Seek(int64(partStart), 0)
-	if err = binary.Write(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Sprintf("Error al escribir superbloque: %v", err)
+	// Escribir bitmap y superbloque
+	if err = alloc.Commit(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 	if err = file.Sync(); err != nil {
 		return fmt.Sprintf("Error syncing disk: %v", err)
 	}
 
-	return fmt.Sprintf("Archivo %s editado exitosamente", fileName)
+	return fmt.Sprintf("Archivo %s editado exitosamente (%d bytes%s)", fileName, inode.ISize, detalle)
 }
 
 // RENAME: Renombra un archivo o carpeta.
@@ -1383,20 +1312,10 @@ func rename(params map[string]string) string {
 	}
 
 	// Verificar si el nuevo nombre ya existe
-	for _, blockIndex := range parentInodeData.IBlock {
-		if blockIndex == -1 {
-			continue
-		}
-		folderBlock, err := readFolderBlock(file, sb, blockIndex)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bloque %d: %v", blockIndex, err)
-		}
-		for _, content := range folderBlock.BContent {
-			name := strings.Trim(string(content.BName[:]), "\x00")
-			if name == name {
-				return fmt.Sprintf("Error: El nombre %s ya existe", name)
-			}
-		}
+	if existing, err := lookupEntry(file, sb, parentInode, name); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	} else if existing != -1 {
+		return fmt.Sprintf("Error: El nombre %s ya existe", name)
 	}
 
 	// Actualizar nombre
@@ -1408,10 +1327,12 @@ func rename(params map[string]string) string {
 		folderBlock.BContent[targetContentIndex].BName[i] = 0
 	}
 	copy(folderBlock.BContent[targetContentIndex].BName[:], name)
-	file.Seek(int64(sb.SBlockStart+targetBlockIndex*sb.SBlockSize), 0)
-	if err = binary.Write(file, binary.LittleEndian, &folderBlock); err != nil {
+	if err = writeFolderBlock(file, sb, targetBlockIndex, &folderBlock); err != nil {
 		return fmt.Sprintf("Error al actualizar bloque %d: %v", targetBlockIndex, err)
 	}
+	if err = touchInode(file, sb, parentInode, false, true); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
 	if err = file.Sync(); err != nil {
 		return fmt.Sprintf("Error syncing disk: %v", err)
 	}
@@ -1495,6 +1416,10 @@ func unmount(params map[string]string) string {
 		}
 	}
 
+	if err = stampMount(file, &mountedPartitions[mpIndex], false); err != nil {
+		fmt.Printf("No se pudo registrar el desmontaje en el superbloque: %v\n", err)
+	}
+
 	// Remover de la lista de particiones montadas
 	mountedPartitions = append(mountedPartitions[:mpIndex], mountedPartitions[mpIndex+1:]...)
 
@@ -1544,71 +1469,27 @@ func loss(params map[string]string) string {
 	}
 
 	// Resetear bitmaps
-	bitmapInodes := make([]byte, sb.SInodesCount)
-	bitmapBlocks := make([]byte, sb.SBlocksCount)
-	bitmapInodes[0], bitmapInodes[1] = 1, 1
-	bitmapBlocks[0], bitmapBlocks[1] = 1, 1
-	file.Seek(int64(sb.SBmInodeStart), 0)
-	if _, err = file.Write(bitmapInodes); err != nil {
+	bitmapInodes := newBitmap(sb.SBmInodeStart, sb.SInodesCount, sb.SVersion)
+	bitmapBlocks := newBitmap(sb.SBmBlockStart, sb.SBlocksCount, sb.SVersion)
+	bitmapInodes.SetRange(0, 2)
+	bitmapBlocks.SetRange(0, 2+sb.SReservedBlocks)
+	if err = bitmapInodes.write(file); err != nil {
 		return fmt.Sprintf("Error al escribir bitmap de inodos: %v", err)
 	}
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	if _, err = file.Write(bitmapBlocks); err != nil {
+	if err = bitmapBlocks.write(file); err != nil {
 		return fmt.Sprintf("Error al escribir bitmap de bloques: %v", err)
 	}
 
 	// Actualizar superbloque
-	mbr, err := readMBR(file)
+	partStart, _, err := getPartitionBounds(file, mp)
 	if err != nil {
-		return fmt.Sprintf("Error al leer MBR: %v", err)
-	}
-	var partStart int32
-	foundPart := false
-	for _, p := range mbr.MbrPartitions {
-		name := strings.Trim(string(p.PartName[:]), "\x00")
-		if name == mp.Name {
-			partStart = p.PartStart
-			foundPart = true
-			break
-		}
-	}
-	if !foundPart {
-		for _, p := range mbr.MbrPartitions {
-			if p.PartType == 'E' {
-				currentPos := p.PartStart
-				for currentPos != -1 {
-					file.Seek(int64(currentPos), 0)
-					var ebr EBR
-					if err = binary.Read(file, binary.LittleEndian, &ebr); err != nil {
-						continue
-					}
-					name := strings.Trim(string(ebr.PartName[:]), "\x00")
-					if name == mp.Name && ebr.PartSize > 0 {
-						partStart = ebr.PartStart
-						foundPart = true
-						break
-					}
-					currentPos = ebr.PartNext
-				}
-			}
-			if foundPart {
-				break
-			}
-		}
-	}
-	if !foundPart {
-		return fmt.Sprintf("Error: Partición %s no encontrada en MBR ni EBR", mp.Name)
-	}
-
-	file.Seek(int64(partStart), 0)
-	var sbUpdated Superblock
-	if err = binary.Read(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Sprintf("Error al leer superbloque: %v", err)
+		return fmt.Sprintf("Error: %v", err)
 	}
-	sbUpdated.SFreeInodesCount = sb.SInodesCount - 2
-	sbUpdated.SFreeBlocksCount = sb.SBlocksCount - 2
-	file.Seek(int64(partStart), 0)
-	if err = binary.Write(file, binary.LittleEndian, &sbUpdated); err != nil {
+	sb.SFreeInodesCount = sb.SInodesCount - 2
+	sb.SFreeBlocksCount = sb.SBlocksCount - 2 - sb.SReservedBlocks
+	sb.SFirstIno = 2
+	sb.SFirstBlo = 2 + sb.SReservedBlocks
+	if err = writeSuperblock(file, partStart, &sb); err != nil {
 		return fmt.Sprintf("Error al escribir superbloque: %v", err)
 	}
 	if err = file.Sync(); err != nil {
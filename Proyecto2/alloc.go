@@ -0,0 +1,589 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Estrategias de asignación (Superblock.SAllocFit)
+const (
+	allocFirstFit = 0 // Busca desde el primer objeto libre (SFirstIno / SFirstBlo)
+	allocNextFit  = 1 // Continúa después de la última asignación
+)
+
+// Allocator centraliza la asignación y liberación de inodos y bloques de una partición.
+// Los cambios se acumulan en memoria y solo se escriben al llamar Commit, de modo que
+// un error a mitad de una operación no deja bitmaps ni contadores inconsistentes.
+type Allocator struct {
+	file      *os.File
+	sb        *Superblock
+	partStart int32
+	inodes    *Bitmap
+	blocks    *Bitmap
+
+	changes  []allocChange // Cambios pendientes en orden, para Commit y Rollback
+	firstIno int32         // Valores de SFirstIno/SFirstBlo al iniciar, para Rollback
+	firstBlo int32
+}
+
+// allocChange registra una asignación o liberación pendiente
+type allocChange struct {
+	inode     bool // true = inodo, false = bloque
+	index     int32
+	allocated bool
+}
+
+// newAllocator lee los bitmaps de la partición y prepara un asignador sobre sb
+func newAllocator(file *os.File, mp *MountedPartition, sb *Superblock) (*Allocator, error) {
+	partStart, _, err := getPartitionBounds(file, mp)
+	if err != nil {
+		return nil, err
+	}
+	inodes, err := readInodeBitmap(file, *sb)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer bitmap de inodos: %v", err)
+	}
+	blocks, err := readBlockBitmap(file, *sb)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer bitmap de bloques: %v", err)
+	}
+	return &Allocator{
+		file:      file,
+		sb:        sb,
+		partStart: partStart,
+		inodes:    inodes,
+		blocks:    blocks,
+		firstIno:  sb.SFirstIno,
+		firstBlo:  sb.SFirstBlo,
+	}, nil
+}
+
+// pending devuelve cuántos inodos o bloques netos se han asignado sin confirmar
+func (a *Allocator) pending(inode bool) int32 {
+	net := int32(0)
+	for _, c := range a.changes {
+		if c.inode != inode {
+			continue
+		}
+		if c.allocated {
+			net++
+		} else {
+			net--
+		}
+	}
+	return net
+}
+
+// FreeInodes devuelve cuántos inodos quedan libres considerando los cambios pendientes
+func (a *Allocator) FreeInodes() int32 {
+	return a.sb.SFreeInodesCount - a.pending(true)
+}
+
+// FreeBlocks devuelve cuántos bloques quedan libres considerando los cambios pendientes
+func (a *Allocator) FreeBlocks() int32 {
+	return a.sb.SFreeBlocksCount - a.pending(false)
+}
+
+// search busca un objeto libre en bm empezando por hint y dando la vuelta al inicio
+func search(bm *Bitmap, hint int32) int32 {
+	if hint < 0 || hint >= bm.Len() {
+		hint = 0
+	}
+	if i := bm.FindFree(hint); i != -1 {
+		return i
+	}
+	return bm.FindFree(0)
+}
+
+// advance actualiza SFirstIno/SFirstBlo después de asignar el objeto i
+func (a *Allocator) advance(bm *Bitmap, first *int32, i int32) {
+	if a.sb.SAllocFit == allocNextFit {
+		*first = i + 1
+		if *first >= bm.Len() {
+			*first = 0
+		}
+		return
+	}
+	if i == *first {
+		*first = search(bm, i)
+	}
+}
+
+// AllocInode reserva un inodo libre
+func (a *Allocator) AllocInode() (int32, error) {
+	if a.FreeInodes() <= 0 {
+		return -1, fmt.Errorf("no hay inodos libres")
+	}
+	i := search(a.inodes, a.sb.SFirstIno)
+	if i == -1 {
+		return -1, fmt.Errorf("no hay inodos libres")
+	}
+	a.inodes.Set(i)
+	a.changes = append(a.changes, allocChange{inode: true, index: i, allocated: true})
+	a.advance(a.inodes, &a.sb.SFirstIno, i)
+	return i, nil
+}
+
+// AllocBlock reserva un bloque libre
+func (a *Allocator) AllocBlock() (int32, error) {
+	if a.FreeBlocks() <= 0 {
+		return -1, fmt.Errorf("no hay bloques libres")
+	}
+	i := search(a.blocks, a.sb.SFirstBlo)
+	if i == -1 {
+		return -1, fmt.Errorf("no hay bloques libres")
+	}
+	a.blocks.Set(i)
+	a.changes = append(a.changes, allocChange{index: i, allocated: true})
+	a.advance(a.blocks, &a.sb.SFirstBlo, i)
+	return i, nil
+}
+
+// AllocContiguous reserva n bloques consecutivos
+func (a *Allocator) AllocContiguous(n int32) ([]int32, error) {
+	if n <= 0 {
+		return nil, nil
+	}
+	if a.FreeBlocks() < n {
+		return nil, fmt.Errorf("no hay bloques libres suficientes (se requieren %d, hay %d)", n, a.FreeBlocks())
+	}
+	start := a.blocks.FindFreeRange(a.sb.SFirstBlo, n)
+	if start == -1 {
+		start = a.blocks.FindFreeRange(0, n)
+	}
+	if start == -1 {
+		return nil, fmt.Errorf("no hay %d bloques contiguos libres", n)
+	}
+	result := make([]int32, n)
+	for k := int32(0); k < n; k++ {
+		a.blocks.Set(start + k)
+		a.changes = append(a.changes, allocChange{index: start + k, allocated: true})
+		result[k] = start + k
+	}
+	if a.sb.SAllocFit == allocNextFit {
+		a.advance(a.blocks, &a.sb.SFirstBlo, start+n-1)
+	} else if start <= a.sb.SFirstBlo && a.sb.SFirstBlo < start+n {
+		// El rango cubrió el primer bloque libre: buscar el siguiente después del rango
+		a.sb.SFirstBlo = search(a.blocks, start+n)
+	}
+	return result, nil
+}
+
+// AllocBlocks reserva n bloques, de forma contigua si es posible
+func (a *Allocator) AllocBlocks(n int32) ([]int32, error) {
+	if blocks, err := a.AllocContiguous(n); err == nil {
+		return blocks, nil
+	}
+	if a.FreeBlocks() < n {
+		return nil, fmt.Errorf("no hay bloques libres suficientes (se requieren %d, hay %d)", n, a.FreeBlocks())
+	}
+	result := make([]int32, 0, n)
+	for k := int32(0); k < n; k++ {
+		b, err := a.AllocBlock()
+		if err != nil {
+			// Devolver lo reservado en esta llamada
+			for _, r := range result {
+				a.FreeBlock(r)
+			}
+			return nil, err
+		}
+		result = append(result, b)
+	}
+	return result, nil
+}
+
+// FreeInode libera un inodo
+func (a *Allocator) FreeInode(i int32) {
+	if !a.inodes.Get(i) {
+		return
+	}
+	a.inodes.Clear(i)
+	a.changes = append(a.changes, allocChange{inode: true, index: i})
+	if a.sb.SAllocFit != allocNextFit && i < a.sb.SFirstIno {
+		a.sb.SFirstIno = i
+	}
+}
+
+// FreeBlock libera un bloque
+func (a *Allocator) FreeBlock(b int32) {
+	if !a.blocks.Get(b) {
+		return
+	}
+	a.blocks.Clear(b)
+	a.changes = append(a.changes, allocChange{index: b})
+	if a.sb.SAllocFit != allocNextFit && b < a.sb.SFirstBlo {
+		a.sb.SFirstBlo = b
+	}
+}
+
+// FreeInodeBlocks libera todos los bloques referenciados por un inodo.
+// El bloque 0 pertenece siempre a la raíz; en inodos antiguos un 0 indica una entrada sin usar.
+func (a *Allocator) FreeInodeBlocks(inode *Inode) int32 {
+	freed := int32(0)
+	for i, blockIndex := range inode.IBlock {
+		if blockIndex > 0 && blockIndex < a.sb.SBlocksCount {
+			a.FreeBlock(blockIndex)
+			freed++
+		}
+		inode.IBlock[i] = -1
+	}
+	return freed
+}
+
+// Rollback descarta las asignaciones y liberaciones pendientes
+func (a *Allocator) Rollback() {
+	// Deshacer en orden inverso por si un mismo objeto se asignó y liberó
+	for k := len(a.changes) - 1; k >= 0; k-- {
+		c := a.changes[k]
+		bm := a.blocks
+		if c.inode {
+			bm = a.inodes
+		}
+		if c.allocated {
+			bm.Clear(c.index)
+		} else {
+			bm.Set(c.index)
+		}
+	}
+	a.changes = nil
+	a.sb.SFirstIno, a.sb.SFirstBlo = a.firstIno, a.firstBlo
+}
+
+// Commit escribe los bitmaps y actualiza los contadores del superbloque
+func (a *Allocator) Commit() error {
+	if err := a.inodes.write(a.file); err != nil {
+		return fmt.Errorf("error al escribir bitmap de inodos: %v", err)
+	}
+	if err := a.blocks.write(a.file); err != nil {
+		return fmt.Errorf("error al escribir bitmap de bloques: %v", err)
+	}
+	a.sb.SFreeInodesCount = a.FreeInodes()
+	a.sb.SFreeBlocksCount = a.FreeBlocks()
+	if err := writeSuperblock(a.file, a.partStart, a.sb); err != nil {
+		return fmt.Errorf("error al escribir superbloque: %v", err)
+	}
+	a.changes = nil
+	a.firstIno, a.firstBlo = a.sb.SFirstIno, a.sb.SFirstBlo
+	return nil
+}
+
+// newInode crea un inodo del tipo indicado, propiedad de la sesión actual y sin bloques
+func newInode(itype byte, perm int32) Inode {
+	inode := Inode{IType: itype, IPerm: perm, ILinks: 1}
+	if currentSession != nil {
+		inode.IUid = currentSession.UserID
+		inode.IGid = currentSession.GroupID
+	}
+	for i := range inode.IBlock {
+		inode.IBlock[i] = -1
+	}
+	fecha := timestamp()
+	inode.IAtime, inode.ICtime, inode.IMtime = fecha, fecha, fecha
+	return inode
+}
+
+// timestamp devuelve la fecha actual con el formato de las fechas de inodos y superbloque
+func timestamp() [19]byte {
+	var fecha [19]byte
+	copy(fecha[:], time.Now().Format("2006-01-02 15:04:05"))
+	return fecha
+}
+
+// touchInode pone la fecha actual como último acceso y/o última modificación del inodo.
+// ICtime guarda la fecha de creación en este sistema de archivos y no cambia.
+func touchInode(file *os.File, sb Superblock, index int32, access, modify bool) error {
+	inode, err := readInode(file, sb, index)
+	if err != nil {
+		return fmt.Errorf("error al leer inodo %d: %v", index, err)
+	}
+	fecha := timestamp()
+	if access {
+		inode.IAtime = fecha
+	}
+	if modify {
+		inode.IMtime = fecha
+	}
+	return writeInode(file, sb, index, &inode)
+}
+
+// addFolderEntry agrega name -> child a la carpeta parentIndex, asignando un bloque nuevo si está llena
+func addFolderEntry(file *os.File, sb Superblock, alloc *Allocator, parentIndex int32, name string, child int32) error {
+	parent, err := readInode(file, sb, parentIndex)
+	if err != nil {
+		return fmt.Errorf("error al leer inodo padre %d: %v", parentIndex, err)
+	}
+	for _, blockIndex := range parent.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(file, sb, blockIndex)
+		if err != nil {
+			return fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
+		}
+		for i := range folderBlock.BContent {
+			entryName := strings.Trim(string(folderBlock.BContent[i].BName[:]), "\x00")
+			if entryName == "" || folderBlock.BContent[i].BInode == -1 {
+				folderBlock.BContent[i].BName = [12]byte{}
+				copy(folderBlock.BContent[i].BName[:], name)
+				folderBlock.BContent[i].BInode = child
+				if err := writeFolderBlock(file, sb, blockIndex, &folderBlock); err != nil {
+					return err
+				}
+				return touchInode(file, sb, parentIndex, false, true)
+			}
+		}
+	}
+
+	// No hay espacio: asignar un nuevo bloque a la carpeta padre
+	slot := -1
+	for i, blockIndex := range parent.IBlock {
+		if blockIndex == -1 {
+			slot = i
+			break
+		}
+	}
+	if slot == -1 {
+		return fmt.Errorf("la carpeta no admite más entradas")
+	}
+	blockIndex, err := alloc.AllocBlock()
+	if err != nil {
+		return fmt.Errorf("%v para la carpeta padre", err)
+	}
+	folderBlock := newFolderBlock(sb)
+	copy(folderBlock.BContent[0].BName[:], name)
+	folderBlock.BContent[0].BInode = child
+	if err := writeFolderBlock(file, sb, blockIndex, &folderBlock); err != nil {
+		return fmt.Errorf("error al escribir bloque %d: %v", blockIndex, err)
+	}
+	parent.IBlock[slot] = blockIndex
+	parent.IMtime = timestamp()
+	return writeInode(file, sb, parentIndex, &parent)
+}
+
+// writeNewInode asigna un inodo de tipo itype con los bloques que necesita content y escribe
+// ambos. No lo agrega a ninguna carpeta; si falla, el llamador debe hacer Rollback.
+func writeNewInode(file *os.File, sb Superblock, alloc *Allocator, itype byte, perm int32, content []byte) (int32, error) {
+	need := (int32(len(content)) + sb.SBlockSize - 1) / sb.SBlockSize
+	if need > int32(len(Inode{}.IBlock)) {
+		return -1, fmt.Errorf("el contenido requiere %d bloques, máximo %d (%d bytes)", need, len(Inode{}.IBlock), int32(len(Inode{}.IBlock))*sb.SBlockSize)
+	}
+	index, err := alloc.AllocInode()
+	if err != nil {
+		return -1, err
+	}
+	blocks, err := alloc.AllocBlocks(need)
+	if err != nil {
+		return -1, err
+	}
+	inode := newInode(itype, perm)
+	inode.ISize = int32(len(content))
+	for k, blockIndex := range blocks {
+		block := newFileBlock(sb)
+		copy(block.BContent, content[int32(k)*sb.SBlockSize:])
+		if err := writeFileBlock(file, sb, blockIndex, &block); err != nil {
+			return -1, fmt.Errorf("error al escribir bloque %d: %v", blockIndex, err)
+		}
+		inode.IBlock[k] = blockIndex
+	}
+	if err := writeInode(file, sb, index, &inode); err != nil {
+		return -1, fmt.Errorf("error al escribir inodo %d: %v", index, err)
+	}
+	return index, nil
+}
+
+// checkFileSize verifica que size bytes quepan en los bloques directos de un inodo. Se
+// compara en int para que un tamaño enorme no se desborde al pasarlo a int32.
+func checkFileSize(sb Superblock, size int) error {
+	maxBlocks := len(Inode{}.IBlock)
+	if maxBytes := maxBlocks * int(sb.SBlockSize); size > maxBytes {
+		return fmt.Errorf("el contenido requiere %d bloques, máximo %d (%d bytes)", (size-1)/int(sb.SBlockSize)+1, maxBlocks, maxBytes)
+	}
+	return nil
+}
+
+// resizeFileContent reemplaza el contenido de un archivo ya existente conservando sus
+// bloques: asigna los que falten si crece y libera los sobrantes si se reduce. Solo
+// actualiza inode en memoria; escribirlo queda a cargo de quien llama.
+func resizeFileContent(file *os.File, sb Superblock, alloc *Allocator, inode *Inode, content []byte) error {
+	if err := checkFileSize(sb, len(content)); err != nil {
+		return err
+	}
+	need := (int32(len(content)) + sb.SBlockSize - 1) / sb.SBlockSize
+
+	var blocks []int32
+	for _, blockIndex := range inode.IBlock {
+		if blockIndex != -1 {
+			blocks = append(blocks, blockIndex)
+		}
+	}
+	if extra := need - int32(len(blocks)); extra > 0 {
+		newBlocks, err := alloc.AllocBlocks(extra)
+		if err != nil {
+			return err
+		}
+		blocks = append(blocks, newBlocks...)
+	}
+	for _, blockIndex := range blocks[need:] {
+		alloc.FreeBlock(blockIndex)
+	}
+	blocks = blocks[:need]
+
+	for k, blockIndex := range blocks {
+		block := newFileBlock(sb)
+		copy(block.BContent, content[int32(k)*sb.SBlockSize:])
+		if err := writeFileBlock(file, sb, blockIndex, &block); err != nil {
+			return fmt.Errorf("error al escribir bloque %d: %v", blockIndex, err)
+		}
+	}
+	for k := range inode.IBlock {
+		inode.IBlock[k] = -1
+		if k < len(blocks) {
+			inode.IBlock[k] = blocks[k]
+		}
+	}
+	inode.ISize = int32(len(content))
+	return nil
+}
+
+// writeNewFolder asigna una carpeta vacía (con . y ..) dentro de parentIndex y la escribe.
+// Igual que writeNewInode, no agrega la entrada en la carpeta padre.
+func writeNewFolder(file *os.File, sb Superblock, alloc *Allocator, parentIndex int32, perm int32) (int32, error) {
+	index, err := alloc.AllocInode()
+	if err != nil {
+		return -1, err
+	}
+	blockIndex, err := alloc.AllocBlock()
+	if err != nil {
+		return -1, err
+	}
+	inode := newInode('0', perm)
+	inode.IBlock[0] = blockIndex
+
+	folderBlock := newFolderBlock(sb)
+	copy(folderBlock.BContent[0].BName[:], ".")
+	folderBlock.BContent[0].BInode = index
+	copy(folderBlock.BContent[1].BName[:], "..")
+	folderBlock.BContent[1].BInode = parentIndex
+
+	if err := writeInode(file, sb, index, &inode); err != nil {
+		return -1, fmt.Errorf("error al escribir inodo %d: %v", index, err)
+	}
+	if err := writeFolderBlock(file, sb, blockIndex, &folderBlock); err != nil {
+		return -1, fmt.Errorf("error al escribir bloque %d: %v", blockIndex, err)
+	}
+	return index, nil
+}
+
+// removeFolderEntry quita la entrada name de la carpeta parentIndex sin liberar su inodo
+func removeFolderEntry(file *os.File, sb Superblock, parentIndex int32, name string) error {
+	parent, err := readInode(file, sb, parentIndex)
+	if err != nil {
+		return fmt.Errorf("error al leer inodo padre %d: %v", parentIndex, err)
+	}
+	for _, blockIndex := range parent.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(file, sb, blockIndex)
+		if err != nil {
+			return fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
+		}
+		for i := range folderBlock.BContent {
+			if strings.Trim(string(folderBlock.BContent[i].BName[:]), "\x00") != name {
+				continue
+			}
+			folderBlock.BContent[i].BName = [12]byte{}
+			folderBlock.BContent[i].BInode = -1
+			if err := writeFolderBlock(file, sb, blockIndex, &folderBlock); err != nil {
+				return fmt.Errorf("error al actualizar bloque %d: %v", blockIndex, err)
+			}
+			return touchInode(file, sb, parentIndex, false, true)
+		}
+	}
+	return fmt.Errorf("%s no existe en la carpeta", name)
+}
+
+// setParentEntry apunta la entrada .. de la carpeta folderIndex a parentIndex, para cuando
+// la carpeta cambia de lugar
+func setParentEntry(file *os.File, sb Superblock, folderIndex, parentIndex int32) error {
+	folder, err := readInode(file, sb, folderIndex)
+	if err != nil {
+		return fmt.Errorf("error al leer inodo %d: %v", folderIndex, err)
+	}
+	if folder.IType != '0' || folder.IBlock[0] == -1 {
+		return nil
+	}
+	folderBlock, err := readFolderBlock(file, sb, folder.IBlock[0])
+	if err != nil {
+		return fmt.Errorf("error al leer bloque %d: %v", folder.IBlock[0], err)
+	}
+	for i := range folderBlock.BContent {
+		if strings.Trim(string(folderBlock.BContent[i].BName[:]), "\x00") == ".." {
+			folderBlock.BContent[i].BInode = parentIndex
+			return writeFolderBlock(file, sb, folder.IBlock[0], &folderBlock)
+		}
+	}
+	return nil
+}
+
+// freeSubtree libera el inodo index y, si es carpeta, todo su contenido. Los archivos con
+// enlaces duros fuera del subárbol solo pierden los enlaces que estaban dentro de él.
+// La entrada que apunta a index en su carpeta padre queda a cargo de quien llama.
+func freeSubtree(file *os.File, sb Superblock, alloc *Allocator, index int32) error {
+	occurrences := map[int32]int32{}
+	if err := countSubtree(file, sb, index, occurrences); err != nil {
+		return err
+	}
+	for child, count := range occurrences {
+		inode, err := readInode(file, sb, child)
+		if err != nil {
+			return fmt.Errorf("error al leer inodo %d: %v", child, err)
+		}
+		if inode.IType == '1' && linkCount(inode) > count {
+			inode.ILinks = linkCount(inode) - count
+			if err := writeInode(file, sb, child, &inode); err != nil {
+				return fmt.Errorf("error al escribir inodo %d: %v", child, err)
+			}
+			continue
+		}
+		alloc.FreeInodeBlocks(&inode)
+		alloc.FreeInode(child)
+	}
+	return nil
+}
+
+// countSubtree cuenta cuántas entradas del subárbol de index apuntan a cada inodo
+func countSubtree(file *os.File, sb Superblock, index int32, occurrences map[int32]int32) error {
+	occurrences[index]++
+	inode, err := readInode(file, sb, index)
+	if err != nil {
+		return fmt.Errorf("error al leer inodo %d: %v", index, err)
+	}
+	if inode.IType != '0' {
+		return nil
+	}
+	if occurrences[index] > 1 {
+		return fmt.Errorf("ciclo detectado en el inodo %d", index)
+	}
+	for _, blockIndex := range inode.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(file, sb, blockIndex)
+		if err != nil {
+			return fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
+		}
+		for _, content := range folderBlock.BContent {
+			name := strings.Trim(string(content.BName[:]), "\x00")
+			if name == "" || name == "." || name == ".." || content.BInode == -1 {
+				continue
+			}
+			if err := countSubtree(file, sb, content.BInode, occurrences); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+)
+
+// Versiones del formato del sistema de archivos (Superblock.SVersion)
+const (
+	fsVersionByteBitmap = 0 // Un byte por objeto en los bitmaps (formato original)
+	fsVersionBitBitmap  = 1 // Un bit por objeto en los bitmaps
+)
+
+// Bitmap representa el bitmap de inodos o de bloques de una partición.
+// Según la versión del superbloque cada objeto ocupa un byte o un bit.
+type Bitmap struct {
+	data   []byte
+	count  int32
+	packed bool
+	start  int32 // Posición absoluta del bitmap en el disco
+}
+
+// bitmapBytes devuelve cuántos bytes ocupa en disco un bitmap de count objetos
+func bitmapBytes(count int32, version int32) int32 {
+	if version == fsVersionBitBitmap {
+		return (count + 7) / 8
+	}
+	return count
+}
+
+// newBitmap crea un bitmap vacío con la representación que indica la versión
+func newBitmap(start, count, version int32) *Bitmap {
+	return &Bitmap{
+		data:   make([]byte, bitmapBytes(count, version)),
+		count:  count,
+		packed: version == fsVersionBitBitmap,
+		start:  start,
+	}
+}
+
+// readBitmap lee un bitmap completo desde el disco
+func readBitmap(file *os.File, start, count, version int32) (*Bitmap, error) {
+	bm := newBitmap(start, count, version)
+	if _, err := file.ReadAt(bm.data, int64(start)); err != nil {
+		return nil, err
+	}
+	return bm, nil
+}
+
+// readInodeBitmap lee el bitmap de inodos de la partición
+func readInodeBitmap(file *os.File, sb Superblock) (*Bitmap, error) {
+	return readBitmap(file, sb.SBmInodeStart, sb.SInodesCount, sb.SVersion)
+}
+
+// readBlockBitmap lee el bitmap de bloques de la partición
+func readBlockBitmap(file *os.File, sb Superblock) (*Bitmap, error) {
+	return readBitmap(file, sb.SBmBlockStart, sb.SBlocksCount, sb.SVersion)
+}
+
+// write escribe el bitmap en su posición del disco
+func (bm *Bitmap) write(file *os.File) error {
+	_, err := file.WriteAt(bm.data, int64(bm.start))
+	return err
+}
+
+// Len devuelve la cantidad de objetos que representa el bitmap
+func (bm *Bitmap) Len() int32 {
+	return bm.count
+}
+
+// Get indica si el objeto i está ocupado
+func (bm *Bitmap) Get(i int32) bool {
+	if i < 0 || i >= bm.count {
+		return false
+	}
+	if bm.packed {
+		return bm.data[i/8]&(1<<uint(i%8)) != 0
+	}
+	return bm.data[i] != 0
+}
+
+// Set marca el objeto i como ocupado
+func (bm *Bitmap) Set(i int32) {
+	if i < 0 || i >= bm.count {
+		return
+	}
+	if bm.packed {
+		bm.data[i/8] |= 1 << uint(i%8)
+	} else {
+		bm.data[i] = 1
+	}
+}
+
+// Clear marca el objeto i como libre
+func (bm *Bitmap) Clear(i int32) {
+	if i < 0 || i >= bm.count {
+		return
+	}
+	if bm.packed {
+		bm.data[i/8] &^= 1 << uint(i%8)
+	} else {
+		bm.data[i] = 0
+	}
+}
+
+// SetRange marca como ocupados los objetos [start, start+n)
+func (bm *Bitmap) SetRange(start, n int32) {
+	for i := start; i < start+n; i++ {
+		bm.Set(i)
+	}
+}
+
+// ClearRange marca como libres los objetos [start, start+n)
+func (bm *Bitmap) ClearRange(start, n int32) {
+	for i := start; i < start+n; i++ {
+		bm.Clear(i)
+	}
+}
+
+// FindFree devuelve el primer objeto libre a partir de from, o -1 si no hay
+func (bm *Bitmap) FindFree(from int32) int32 {
+	if from < 0 {
+		from = 0
+	}
+	for i := from; i < bm.count; i++ {
+		// En bitmaps empaquetados se saltan los bytes llenos completos
+		if bm.packed && i%8 == 0 && i+8 <= bm.count && bm.data[i/8] == 0xFF {
+			i += 7
+			continue
+		}
+		if !bm.Get(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// FindFreeRange devuelve el inicio de la primera corrida de n objetos libres
+// consecutivos a partir de from, o -1 si no existe
+func (bm *Bitmap) FindFreeRange(from, n int32) int32 {
+	if n <= 0 {
+		return -1
+	}
+	run := int32(0)
+	for i := bm.FindFree(from); i >= 0 && i < bm.count; i++ {
+		if bm.Get(i) {
+			run = 0
+			continue
+		}
+		run++
+		if run == n {
+			return i - n + 1
+		}
+	}
+	return -1
+}
+
+// IsFreeRange indica si todos los objetos [start, start+n) están libres
+func (bm *Bitmap) IsFreeRange(start, n int32) bool {
+	if start < 0 || start+n > bm.count {
+		return false
+	}
+	for i := start; i < start+n; i++ {
+		if bm.Get(i) {
+			return false
+		}
+	}
+	return true
+}
+
+// CountUsed devuelve cuántos objetos están ocupados en [start, start+n)
+func (bm *Bitmap) CountUsed(start, n int32) int32 {
+	used := int32(0)
+	for i := start; i < start+n && i < bm.count; i++ {
+		if bm.Get(i) {
+			used++
+		}
+	}
+	return used
+}
+
+// CountFree devuelve cuántos objetos libres tiene el bitmap
+func (bm *Bitmap) CountFree() int32 {
+	return bm.count - bm.CountUsed(0, bm.count)
+}
@@ -0,0 +1,279 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// diskIssue describe un problema encontrado en la tabla de particiones
+type diskIssue struct {
+	Desc string
+	Fix  func() error // nil si el problema no se puede corregir de forma segura
+}
+
+// partExtent representa el rango ocupado por una partición del disco
+type partExtent struct {
+	Name  string
+	Start int32
+	End   int32
+}
+
+// CHECKDISK: Valida la tabla de particiones (MBR y EBR) de un disco.
+func checkdisk(params map[string]string) string {
+	path, hasPath := params["path"]
+	if !hasPath {
+		return "Error: Parámetro -path es obligatorio"
+	}
+	_, fix := params["fix"]
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	mbr, err := readMBR(file)
+	if err != nil {
+		return fmt.Sprintf("Error al leer MBR: %v", err)
+	}
+
+	issues := checkPartitionTable(file, path, mbr)
+	if len(issues) == 0 {
+		return fmt.Sprintf("Disco %s sin problemas", path)
+	}
+
+	var salida strings.Builder
+	salida.WriteString(fmt.Sprintf("Disco %s: %d problema(s) encontrado(s)", path, len(issues)))
+	fixed := 0
+	for _, issue := range issues {
+		salida.WriteString("\nProblema: " + issue.Desc)
+		if issue.Fix == nil {
+			continue
+		}
+		if !fix {
+			salida.WriteString(" (corregible con -fix)")
+			continue
+		}
+		if err := issue.Fix(); err != nil {
+			salida.WriteString(fmt.Sprintf(" (error al corregir: %v)", err))
+			continue
+		}
+		salida.WriteString(" (corregido)")
+		fixed++
+	}
+	if fix {
+		salida.WriteString(fmt.Sprintf("\nSe corrigieron %d problema(s)", fixed))
+	}
+	return salida.String()
+}
+
+// checkPartitionTable revisa el MBR y la cadena de EBR, devolviendo los problemas encontrados
+func checkPartitionTable(file *os.File, path string, mbr *MBR) []diskIssue {
+	var issues []diskIssue
+	mbrSize := int32(binary.Size(MBR{}))
+	ebrSize := int32(binary.Size(EBR{}))
+
+	names := make(map[string]int)
+	type idOwner struct {
+		Name     string
+		MBRIndex int   // índice en el MBR, -1 si es lógica
+		EBRPos   int64 // posición del EBR si es lógica
+	}
+	ids := make(map[string][]idOwner)
+
+	// Particiones primarias y extendidas
+	var extents []partExtent
+	var extended []Partition
+	for i, part := range mbr.MbrPartitions {
+		if part.PartStatus != '1' {
+			continue
+		}
+		name := strings.Trim(string(part.PartName[:]), "\x00")
+		names[name]++
+		if id := strings.Trim(string(part.PartID[:]), "\x00"); id != "" {
+			ids[id] = append(ids[id], idOwner{Name: name, MBRIndex: i})
+		}
+
+		if part.PartType != 'P' && part.PartType != 'E' {
+			issues = append(issues, diskIssue{Desc: fmt.Sprintf("La partición %s tiene un tipo inválido (%q)", name, part.PartType)})
+		}
+		if part.PartSize <= 0 {
+			issues = append(issues, diskIssue{Desc: fmt.Sprintf("La partición %s tiene tamaño inválido (%d)", name, part.PartSize)})
+			continue
+		}
+		if part.PartStart < mbrSize {
+			issues = append(issues, diskIssue{Desc: fmt.Sprintf("La partición %s inicia dentro del MBR (byte %d)", name, part.PartStart)})
+		}
+		if part.PartStart+part.PartSize > mbr.MbrTamano {
+			issues = append(issues, diskIssue{Desc: fmt.Sprintf("La partición %s termina en el byte %d, después del fin del disco (%d)", name, part.PartStart+part.PartSize, mbr.MbrTamano)})
+		}
+		extents = append(extents, partExtent{Name: name, Start: part.PartStart, End: part.PartStart + part.PartSize})
+		if part.PartType == 'E' {
+			extended = append(extended, part)
+		}
+	}
+
+	if len(extended) > 1 {
+		issues = append(issues, diskIssue{Desc: fmt.Sprintf("El disco tiene %d particiones extendidas, solo se permite una", len(extended))})
+	}
+	issues = append(issues, overlappingExtents(extents)...)
+
+	// Particiones lógicas dentro de cada extendida
+	for _, ext := range extended {
+		extName := strings.Trim(string(ext.PartName[:]), "\x00")
+		extEnd := ext.PartStart + ext.PartSize
+		visited := make(map[int32]bool)
+		var logicals []partExtent
+		var prevPos int64 = -1
+		var prevEBR EBR
+		pos := ext.PartStart
+
+		// truncate corta la cadena en el último EBR válido
+		truncate := func(at int64, ebr EBR) func() error {
+			if at == -1 {
+				return nil
+			}
+			return func() error {
+				ebr.PartNext = -1
+				return writeEBR(file, at, &ebr)
+			}
+		}
+
+		for {
+			if visited[pos] {
+				issues = append(issues, diskIssue{
+					Desc: fmt.Sprintf("Cadena de EBR cíclica en %s: el EBR en %d apunta de nuevo a %d", extName, prevPos, pos),
+					Fix:  truncate(prevPos, prevEBR),
+				})
+				break
+			}
+			if pos < ext.PartStart || pos+ebrSize > extEnd {
+				issues = append(issues, diskIssue{
+					Desc: fmt.Sprintf("Cadena de EBR rota en %s: PartNext=%d está fuera de la extendida (%d-%d)", extName, pos, ext.PartStart, extEnd),
+					Fix:  truncate(prevPos, prevEBR),
+				})
+				break
+			}
+			ebr, err := readEBR(file, int64(pos))
+			if err != nil {
+				issues = append(issues, diskIssue{
+					Desc: fmt.Sprintf("No se pudo leer el EBR en %d: %v", pos, err),
+					Fix:  truncate(prevPos, prevEBR),
+				})
+				break
+			}
+			visited[pos] = true
+
+			if ebr.PartSize > 0 {
+				name := strings.Trim(string(ebr.PartName[:]), "\x00")
+				names[name]++
+				if id := strings.Trim(string(ebr.PartID[:]), "\x00"); id != "" {
+					ids[id] = append(ids[id], idOwner{Name: name, MBRIndex: -1, EBRPos: int64(pos)})
+				}
+				if ebr.PartStart != pos {
+					issues = append(issues, diskIssue{Desc: fmt.Sprintf("El EBR en %d de la lógica %s indica PartStart=%d", pos, name, ebr.PartStart)})
+				}
+				// Igual que fdisk, cada lógica ocupa su tamaño más el EBR que la describe
+				end := ebr.PartStart + ebr.PartSize + ebrSize
+				if ebr.PartStart < ext.PartStart || end > extEnd {
+					issues = append(issues, diskIssue{Desc: fmt.Sprintf("La lógica %s (%d-%d) está fuera de la extendida %s (%d-%d)", name, ebr.PartStart, end, extName, ext.PartStart, extEnd)})
+				}
+				logicals = append(logicals, partExtent{Name: name, Start: ebr.PartStart, End: end})
+			}
+
+			if ebr.PartNext == -1 {
+				break
+			}
+			prevPos = int64(pos)
+			prevEBR = ebr
+			pos = ebr.PartNext
+		}
+		issues = append(issues, overlappingExtents(logicals)...)
+	}
+
+	for _, name := range sortedKeys(names) {
+		if count := names[name]; count > 1 {
+			issues = append(issues, diskIssue{Desc: fmt.Sprintf("El nombre %s está repetido en %d particiones", name, count)})
+		}
+	}
+
+	for _, id := range sortedKeys(ids) {
+		owners := ids[id]
+		if len(owners) < 2 {
+			continue
+		}
+		var ownerNames []string
+		for _, o := range owners {
+			ownerNames = append(ownerNames, o.Name)
+		}
+		// Solo se limpian los IDs que no pertenecen a una partición montada
+		var stale []idOwner
+		for _, o := range owners {
+			isMounted := false
+			for _, mp := range mountedPartitions {
+				if mp.Path == path && mp.ID == id && mp.Name == o.Name {
+					isMounted = true
+					break
+				}
+			}
+			if !isMounted {
+				stale = append(stale, o)
+			}
+		}
+		issues = append(issues, diskIssue{
+			Desc: fmt.Sprintf("El ID %s está repetido en las particiones %s", id, strings.Join(ownerNames, ", ")),
+			Fix: func() error {
+				for _, o := range stale {
+					if o.MBRIndex >= 0 {
+						mbr.MbrPartitions[o.MBRIndex].PartID = [4]byte{}
+						mbr.MbrPartitions[o.MBRIndex].PartCorrel = -1
+						if err := writeMBR(file, mbr); err != nil {
+							return err
+						}
+						continue
+					}
+					ebr, err := readEBR(file, o.EBRPos)
+					if err != nil {
+						return err
+					}
+					ebr.PartID = [4]byte{}
+					ebr.PartCorrel = -1
+					ebr.PartMount = '0'
+					if err := writeEBR(file, o.EBRPos, &ebr); err != nil {
+						return err
+					}
+				}
+				return nil
+			},
+		})
+	}
+
+	return issues
+}
+
+// overlappingExtents reporta las particiones que se traslapan entre sí
+func overlappingExtents(extents []partExtent) []diskIssue {
+	var issues []diskIssue
+	sorted := append([]partExtent(nil), extents...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	for i := 0; i < len(sorted); i++ {
+		for j := i + 1; j < len(sorted) && sorted[j].Start < sorted[i].End; j++ {
+			issues = append(issues, diskIssue{Desc: fmt.Sprintf("Las particiones %s (%d-%d) y %s (%d-%d) se traslapan",
+				sorted[i].Name, sorted[i].Start, sorted[i].End, sorted[j].Name, sorted[j].Start, sorted[j].End)})
+		}
+	}
+	return issues
+}
+
+// sortedKeys devuelve las llaves de un mapa en orden alfabético
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// treeCopy copia un subárbol de una partición a otra o a otro lugar de la misma.
+// Los elementos se recolectan con collectExport; hostPath guarda la ruta relativa a la raíz
+// copiada (la raíz es ".").
+type treeCopy struct {
+	src, dst     *os.File
+	srcSB, dstSB Superblock
+	entries      []exportEntry
+	skipped      []string
+	keepOwner    bool // Solo root conserva el dueño y grupo originales
+	keepTimes    bool // move conserva las fechas, copy crea elementos nuevos
+}
+
+// COPY: Copia un archivo, enlace o carpeta completa, también hacia otra partición con -srcid y -destid.
+func copyMap(params map[string]string) string {
+	return transfer(params, false)
+}
+
+// transfer implementa copy y, con removeSource, el move entre particiones distintas
+func transfer(params map[string]string, removeSource bool) string {
+	src, hasSrc := params["path"]
+	dest, hasDest := params["dest"]
+	srcID, hasSrcID := params["srcid"]
+	destID, hasDestID := params["destid"]
+	if !hasSrcID {
+		srcID, hasSrcID = params["id"]
+	}
+	if !hasDestID {
+		destID, hasDestID = params["id"]
+	}
+	if !hasSrc || !hasDest || !hasSrcID || !hasDestID {
+		return "Error: Parámetros -path, -dest y -id (o -srcid y -destid) son obligatorios"
+	}
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	// Verificar particiones montadas
+	var srcMp, dstMp *MountedPartition
+	for _, p := range mountedPartitions {
+		mp := p
+		if p.ID == srcID {
+			srcMp = &mp
+		}
+		if p.ID == destID {
+			dstMp = &mp
+		}
+	}
+	if srcMp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", srcID)
+	}
+	if dstMp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", destID)
+	}
+
+	c := &treeCopy{keepOwner: currentSession.Username == "root", keepTimes: removeSource}
+	var err error
+	c.src, err = os.OpenFile(srcMp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer c.src.Close()
+	c.dst = c.src
+	if srcID != destID {
+		if c.dst, err = os.OpenFile(dstMp.Path, os.O_RDWR, 0644); err != nil {
+			return fmt.Sprintf("Error al abrir disco: %v", err)
+		}
+		defer c.dst.Close()
+	}
+	if c.srcSB, err = readSuperblock(c.src, srcMp); err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+	if c.dstSB, err = readSuperblock(c.dst, dstMp); err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	// Procesar rutas
+	srcParts, err := normalizePath(src)
+	if err != nil {
+		return fmt.Sprintf("Error en ruta fuente: %v", err)
+	}
+	destParts, err := normalizePath(dest)
+	if err != nil {
+		return fmt.Sprintf("Error en ruta destino: %v", err)
+	}
+	srcPath := "/" + strings.Join(srcParts, "/")
+	destPath := "/" + strings.Join(destParts, "/")
+	if srcID == destID && (destPath == srcPath || strings.HasPrefix(destPath, srcPath+"/")) {
+		return fmt.Sprintf("Error: No se puede copiar %s dentro de sí mismo", srcPath)
+	}
+
+	// Origen: el último elemento no se sigue, un enlace simbólico se copia como enlace
+	srcName := srcParts[len(srcParts)-1]
+	srcParent, err := navigateToParent(c.src, c.srcSB, srcParts[:len(srcParts)-1])
+	if err != nil {
+		return fmt.Sprintf("Error al navegar a la carpeta padre del origen: %v", err)
+	}
+	srcIndex, err := lookupEntry(c.src, c.srcSB, srcParent, srcName)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if srcIndex == -1 {
+		return fmt.Sprintf("Error: %s no encontrado", srcName)
+	}
+	srcInode, err := readInode(c.src, c.srcSB, srcIndex)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", srcIndex, err)
+	}
+	if srcInode.IType != '2' && !hasReadPermission(srcInode, currentSession) {
+		return fmt.Sprintf("Error: Permisos insuficientes para leer %s", srcName)
+	}
+	if removeSource {
+		parentInode, err := readInode(c.src, c.srcSB, srcParent)
+		if err != nil {
+			return fmt.Sprintf("Error al leer inodo padre: %v", err)
+		}
+		if !hasWritePermission(parentInode, currentSession.UserID, currentSession.GroupID) ||
+			!hasWritePermission(srcInode, currentSession.UserID, currentSession.GroupID) {
+			return fmt.Sprintf("Error: Permisos insuficientes para mover %s", srcName)
+		}
+	}
+
+	// Destino: la carpeta padre debe existir y no puede haber nada con el mismo nombre
+	destName := destParts[len(destParts)-1]
+	destParent, err := navigateToParent(c.dst, c.dstSB, destParts[:len(destParts)-1])
+	if err != nil {
+		return fmt.Sprintf("Error al navegar a la carpeta destino: %v", err)
+	}
+	destParentInode, err := readInode(c.dst, c.dstSB, destParent)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo destino: %v", err)
+	}
+	if destParentInode.IType != '0' {
+		return "Error: La carpeta destino no es una carpeta"
+	}
+	if !hasWritePermission(destParentInode, currentSession.UserID, currentSession.GroupID) {
+		return "Error: Permisos insuficientes para escribir en la carpeta destino"
+	}
+	if existing, err := lookupEntry(c.dst, c.dstSB, destParent, destName); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	} else if existing != -1 {
+		return fmt.Sprintf("Error: %s ya existe en la ruta destino", destName)
+	}
+
+	// Recolectar el subárbol; move no puede dejar elementos atrás
+	if err = collectExport(c.src, c.srcSB, srcIndex, srcInode, ".", strings.TrimSuffix(srcPath, "/"), &c.entries, &c.skipped, map[int32]bool{}); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if removeSource && len(c.skipped) > 0 {
+		return fmt.Sprintf("Error: No se puede mover %s, sin permiso de lectura en: %s", srcPath, strings.Join(c.skipped, ", "))
+	}
+
+	// Verificar el espacio en el destino antes de escribir
+	alloc, err := newAllocator(c.dst, dstMp, &c.dstSB)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	inodes, blocks, err := c.required(destParent)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if inodes > alloc.FreeInodes() || blocks > alloc.FreeBlocks() {
+		return fmt.Sprintf("Error: Espacio insuficiente en %s: se requieren %d inodos y %d bloques, hay %d y %d libres",
+			destID, inodes, blocks, alloc.FreeInodes(), alloc.FreeBlocks())
+	}
+
+	if err = c.write(alloc, destParent, destName); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v (no se modificó el destino)", err)
+	}
+	if err = alloc.Commit(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = c.dst.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+
+	if removeSource {
+		if err = c.removeSource(srcMp, srcParent, srcName); err != nil {
+			return fmt.Sprintf("Error: %s se copió a %s:%s pero no se pudo eliminar el origen: %v", srcPath, destID, destPath, err)
+		}
+		return fmt.Sprintf("%s movido exitosamente a %s:%s (%d elementos)", srcPath, destID, destPath, len(c.entries))
+	}
+
+	salida := fmt.Sprintf("%s copiado exitosamente a %s:%s (%d elementos)", srcPath, destID, destPath, len(c.entries))
+	if len(c.skipped) > 0 {
+		salida += fmt.Sprintf("\nOmitidos por falta de permiso de lectura: %s", strings.Join(c.skipped, ", "))
+	}
+	return salida
+}
+
+// required calcula los inodos y bloques que ocupará la copia en el destino, incluido el
+// bloque que podría necesitar la carpeta destParent para la nueva entrada
+func (c *treeCopy) required(destParent int32) (int32, int32, error) {
+	perBlock := int32(len(newFolderBlock(c.dstSB).BContent))
+	maxBlocks := int32(len(Inode{}.IBlock))
+	children := map[string]int32{}
+	for _, e := range c.entries[1:] {
+		children[filepath.Dir(e.hostPath)]++
+	}
+
+	var inodes, blocks int32
+	seen := map[int32]bool{}
+	for _, e := range c.entries {
+		if e.inode.IType == '0' {
+			// . y .. más las entradas de la carpeta
+			need := (2 + children[e.hostPath] + perBlock - 1) / perBlock
+			if need > maxBlocks {
+				return 0, 0, fmt.Errorf("la carpeta %s tiene demasiadas entradas para el tamaño de bloque del destino", e.hostPath)
+			}
+			inodes++
+			blocks += need
+			continue
+		}
+		if seen[e.index] {
+			continue // Enlace duro: se agrega solo la entrada
+		}
+		seen[e.index] = true
+		need := (e.inode.ISize + c.dstSB.SBlockSize - 1) / c.dstSB.SBlockSize
+		if need > maxBlocks {
+			return 0, 0, fmt.Errorf("%s requiere %d bloques en el destino, máximo %d", e.hostPath, need, maxBlocks)
+		}
+		inodes++
+		blocks += need
+	}
+
+	hasSlot, err := folderHasFreeSlot(c.dst, c.dstSB, destParent)
+	if err != nil {
+		return 0, 0, err
+	}
+	if !hasSlot {
+		blocks++
+	}
+	return inodes, blocks, nil
+}
+
+// folderHasFreeSlot indica si la carpeta tiene alguna entrada libre en sus bloques actuales
+func folderHasFreeSlot(file *os.File, sb Superblock, index int32) (bool, error) {
+	inode, err := readInode(file, sb, index)
+	if err != nil {
+		return false, fmt.Errorf("error al leer inodo %d: %v", index, err)
+	}
+	for _, blockIndex := range inode.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(file, sb, blockIndex)
+		if err != nil {
+			return false, fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
+		}
+		for _, content := range folderBlock.BContent {
+			if strings.Trim(string(content.BName[:]), "\x00") == "" || content.BInode == -1 {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// write crea la copia en el destino. La raíz se enlaza en destParent al final, así que si
+// algo falla antes basta con deshacer el asignador para no dejar rastro.
+func (c *treeCopy) write(alloc *Allocator, destParent int32, destName string) error {
+	created := map[int32]int32{} // Inodo de origen -> inodo copiado
+	folders := map[string]int32{}
+	var rootIndex int32
+	for k, e := range c.entries {
+		parent := destParent
+		if k > 0 {
+			parent = folders[filepath.Dir(e.hostPath)]
+		}
+
+		// Un enlace duro dentro del subárbol apunta a la copia ya creada
+		if index, ok := created[e.index]; ok && e.inode.IType != '0' {
+			inode, err := readInode(c.dst, c.dstSB, index)
+			if err != nil {
+				return fmt.Errorf("error al leer inodo %d: %v", index, err)
+			}
+			inode.ILinks = linkCount(inode) + 1
+			if err := writeInode(c.dst, c.dstSB, index, &inode); err != nil {
+				return fmt.Errorf("error al escribir inodo %d: %v", index, err)
+			}
+			if err := addFolderEntry(c.dst, c.dstSB, alloc, parent, filepath.Base(e.hostPath), index); err != nil {
+				return fmt.Errorf("%s: %v", e.hostPath, err)
+			}
+			continue
+		}
+
+		var index int32
+		var err error
+		if e.inode.IType == '0' {
+			index, err = writeNewFolder(c.dst, c.dstSB, alloc, parent, e.inode.IPerm)
+			folders[e.hostPath] = index
+		} else {
+			var content []byte
+			if content, err = readFileContent(c.src, c.srcSB, e.inode); err != nil {
+				return fmt.Errorf("error al leer %s: %v", e.hostPath, err)
+			}
+			index, err = writeNewInode(c.dst, c.dstSB, alloc, e.inode.IType, e.inode.IPerm, content)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", e.hostPath, err)
+		}
+		created[e.index] = index
+		if k == 0 {
+			rootIndex = index
+		} else if err := addFolderEntry(c.dst, c.dstSB, alloc, parent, filepath.Base(e.hostPath), index); err != nil {
+			return fmt.Errorf("%s: %v", e.hostPath, err)
+		}
+	}
+
+	// Dueño y fechas al final, porque agregar entradas cambia la fecha de modificación de las carpetas
+	if c.keepOwner || c.keepTimes {
+		for srcIndex, index := range created {
+			original, err := readInode(c.src, c.srcSB, srcIndex)
+			if err != nil {
+				return fmt.Errorf("error al leer inodo %d: %v", srcIndex, err)
+			}
+			inode, err := readInode(c.dst, c.dstSB, index)
+			if err != nil {
+				return fmt.Errorf("error al leer inodo %d: %v", index, err)
+			}
+			if c.keepOwner {
+				inode.IUid, inode.IGid = original.IUid, original.IGid
+			}
+			if c.keepTimes {
+				inode.IAtime, inode.ICtime, inode.IMtime = original.IAtime, original.ICtime, original.IMtime
+			}
+			if err := writeInode(c.dst, c.dstSB, index, &inode); err != nil {
+				return fmt.Errorf("error al escribir inodo %d: %v", index, err)
+			}
+		}
+	}
+
+	return addFolderEntry(c.dst, c.dstSB, alloc, destParent, destName, rootIndex)
+}
+
+// removeSource elimina de la partición de origen el subárbol ya copiado
+func (c *treeCopy) removeSource(mp *MountedPartition, parent int32, name string) error {
+	alloc, err := newAllocator(c.src, mp, &c.srcSB)
+	if err != nil {
+		return err
+	}
+	if err := freeSubtree(c.src, c.srcSB, alloc, c.entries[0].index); err != nil {
+		alloc.Rollback()
+		return err
+	}
+	if err := removeFolderEntry(c.src, c.srcSB, parent, name); err != nil {
+		alloc.Rollback()
+		return err
+	}
+	if err := alloc.Commit(); err != nil {
+		return err
+	}
+	return c.src.Sync()
+}
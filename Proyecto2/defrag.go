@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+)
+
+// DEFRAG: Compacta las particiones hacia el inicio del disco para consolidar el espacio libre.
+func defrag(params map[string]string) string {
+	path, hasPath := params["path"]
+	if !hasPath {
+		return "Error: Parámetro -path es obligatorio"
+	}
+
+	for _, mp := range mountedPartitions {
+		if mp.Path == path {
+			return fmt.Sprintf("Error: La partición %s (ID %s) está montada, desmóntela antes de desfragmentar", mp.Name, mp.ID)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	mbr, err := readMBR(file)
+	if err != nil {
+		return fmt.Sprintf("Error al leer MBR: %v", err)
+	}
+
+	// No mover nada si la tabla de particiones está dañada
+	if issues := checkPartitionTable(file, path, mbr); len(issues) > 0 {
+		return fmt.Sprintf("Error: La tabla de particiones tiene %d problema(s), ejecute checkdisk antes de desfragmentar", len(issues))
+	}
+
+	var order []int
+	for i, part := range mbr.MbrPartitions {
+		if part.PartStatus == '1' && part.PartSize > 0 {
+			order = append(order, i)
+		}
+	}
+	sort.Slice(order, func(a, b int) bool {
+		return mbr.MbrPartitions[order[a]].PartStart < mbr.MbrPartitions[order[b]].PartStart
+	})
+
+	var salida strings.Builder
+	moved := 0
+	cursor := int32(binary.Size(MBR{}))
+	for _, i := range order {
+		part := mbr.MbrPartitions[i]
+		name := strings.Trim(string(part.PartName[:]), "\x00")
+		delta := part.PartStart - cursor
+		if delta > 0 {
+			if err := moveRange(file, int64(part.PartStart), int64(cursor), int64(part.PartSize)); err != nil {
+				return fmt.Sprintf("Error al mover la partición %s: %v", name, err)
+			}
+			if part.PartType == 'E' {
+				if err := relocateEBRChain(file, cursor, delta); err != nil {
+					return fmt.Sprintf("Error al actualizar los EBR de %s: %v", name, err)
+				}
+			} else if err := relocateFilesystem(file, cursor, delta); err != nil {
+				return fmt.Sprintf("Error al actualizar el superbloque de %s: %v", name, err)
+			}
+
+			mbr.MbrPartitions[i].PartStart = cursor
+			if err := writeMBR(file, mbr); err != nil {
+				return fmt.Sprintf("Error al escribir MBR: %v", err)
+			}
+			salida.WriteString(fmt.Sprintf("Partición %s movida del byte %d al %d\n", name, part.PartStart, cursor))
+			moved++
+		}
+		cursor += part.PartSize
+	}
+
+	if err := file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+
+	if moved == 0 {
+		salida.WriteString("El disco ya está compactado\n")
+	}
+	salida.WriteString(fmt.Sprintf("Espacio libre contiguo: %d bytes (bytes %d a %d)", mbr.MbrTamano-cursor, cursor, mbr.MbrTamano))
+	return salida.String()
+}
+
+// moveRange copia size bytes desde src hacia dst (dst < src) y limpia la región liberada
+func moveRange(file *os.File, src, dst, size int64) error {
+	if err := copyRange(file, src, dst, size); err != nil {
+		return err
+	}
+
+	// Limpiar la cola que quedó libre para no dejar estructuras viejas
+	clearStart := dst + size
+	if clearStart < src {
+		clearStart = src
+	}
+	return zeroRange(file, clearStart, src+size-clearStart)
+}
+
+// copyRange copia size bytes desde src hacia dst, aunque ambos rangos se traslapen
+func copyRange(file *os.File, src, dst, size int64) error {
+	buffer := make([]byte, 64*1024)
+	for done := int64(0); done < size; {
+		n := int64(len(buffer))
+		if size-done < n {
+			n = size - done
+		}
+		// Hacia adelante se copia desde el final para no pisar bytes aún no leídos
+		offset := done
+		if dst > src {
+			offset = size - done - n
+		}
+		if _, err := file.ReadAt(buffer[:n], src+offset); err != nil && err != io.EOF {
+			return err
+		}
+		if _, err := file.WriteAt(buffer[:n], dst+offset); err != nil {
+			return err
+		}
+		done += n
+	}
+	return nil
+}
+
+// zeroRange escribe ceros en el rango indicado
+func zeroRange(file *os.File, start, size int64) error {
+	buffer := make([]byte, 64*1024)
+	for offset := int64(0); offset < size; {
+		n := int64(len(buffer))
+		if size-offset < n {
+			n = size - offset
+		}
+		if _, err := file.WriteAt(buffer[:n], start+offset); err != nil {
+			return err
+		}
+		offset += n
+	}
+	return nil
+}
+
+// relocateFilesystem ajusta los punteros absolutos del superbloque de una partición desplazada delta bytes
+func relocateFilesystem(file *os.File, partStart int32, delta int32) error {
+	var sb Superblock
+	if _, err := file.Seek(int64(partStart), 0); err != nil {
+		return err
+	}
+	if err := binary.Read(file, binary.LittleEndian, &sb); err != nil {
+		return err
+	}
+	if sb.SMagic != 0xEF53 {
+		return nil // Partición sin formato
+	}
+
+	sb.SBmInodeStart -= delta
+	sb.SBmBlockStart -= delta
+	sb.SInodeStart -= delta
+	sb.SBlockStart -= delta
+	if sb.SJournalStart > 0 {
+		sb.SJournalStart -= delta
+	}
+
+	return writeSuperblock(file, partStart, &sb)
+}
+
+// relocateEBRChain ajusta las posiciones de los EBR de una partición extendida desplazada delta
+// bytes y los superbloques de sus particiones lógicas formateadas
+func relocateEBRChain(file *os.File, extStart int32, delta int32) error {
+	pos := int64(extStart)
+	for {
+		ebr, err := readEBR(file, pos)
+		if err != nil {
+			return err
+		}
+		ebr.PartStart -= delta
+		if ebr.PartNext != -1 {
+			ebr.PartNext -= delta
+		}
+		if err := writeEBR(file, pos, &ebr); err != nil {
+			return err
+		}
+		if ebr.PartSize > 0 {
+			if err := relocateFilesystem(file, ebr.PartStart, delta); err != nil {
+				return fmt.Errorf("superbloque de la partición lógica %s: %v", strings.Trim(string(ebr.PartName[:]), "\x00"), err)
+			}
+		}
+		if ebr.PartNext == -1 {
+			return nil
+		}
+		pos = int64(ebr.PartNext)
+	}
+}
@@ -0,0 +1,221 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// humanSize muestra una cantidad de bytes con la unidad más adecuada (B, K o M)
+func humanSize(bytes int64) string {
+	switch {
+	case bytes >= 1024*1024:
+		return fmt.Sprintf("%.1fM", float64(bytes)/(1024*1024))
+	case bytes >= 1024:
+		return fmt.Sprintf("%.1fK", float64(bytes)/1024)
+	}
+	return fmt.Sprintf("%dB", bytes)
+}
+
+// DF: Muestra el uso de inodos y bloques de todas las particiones montadas.
+func df(params map[string]string) string {
+	if len(mountedPartitions) == 0 {
+		return "No hay particiones montadas"
+	}
+
+	var salida strings.Builder
+	var warnings []string
+	salida.WriteString(fmt.Sprintf("%-6s %-16s %8s %8s %8s %8s %8s %8s %5s",
+		"ID", "Nombre", "Inodos", "Usados", "Libres", "Bloques", "Usados", "Libres", "Uso%"))
+	for _, p := range mountedPartitions {
+		mp := p
+		line, warning := dfPartition(&mp)
+		salida.WriteString("\n" + line)
+		if warning != "" {
+			warnings = append(warnings, warning)
+		}
+	}
+	for _, w := range warnings {
+		salida.WriteString("\n" + w)
+	}
+	return salida.String()
+}
+
+// dfPartition arma la fila de df para una partición y, si los contadores del superbloque
+// no coinciden con los bitmaps, un aviso
+func dfPartition(mp *MountedPartition) (string, string) {
+	file, err := os.Open(mp.Path)
+	if err != nil {
+		return fmt.Sprintf("%-6s %-16s error al abrir disco: %v", mp.ID, mp.Name, err), ""
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("%-6s %-16s sin sistema de archivos", mp.ID, mp.Name), ""
+	}
+
+	usedInodes := sb.SInodesCount - sb.SFreeInodesCount
+	usedBlocks := sb.SBlocksCount - sb.SFreeBlocksCount
+	percent := int32(0)
+	if sb.SBlocksCount > 0 {
+		percent = usedBlocks * 100 / sb.SBlocksCount
+	}
+	line := fmt.Sprintf("%-6s %-16s %8d %8d %8d %8d %8d %8d %4d%%",
+		mp.ID, mp.Name, sb.SInodesCount, usedInodes, sb.SFreeInodesCount,
+		sb.SBlocksCount, usedBlocks, sb.SFreeBlocksCount, percent)
+
+	// Comparar con los bitmaps, igual que fsck
+	var problems []string
+	if bm, err := readInodeBitmap(file, sb); err != nil {
+		problems = append(problems, fmt.Sprintf("no se pudo leer el bitmap de inodos: %v", err))
+	} else if free := bm.CountFree(); free != sb.SFreeInodesCount {
+		problems = append(problems, fmt.Sprintf("el superbloque indica %d inodos libres y el bitmap %d", sb.SFreeInodesCount, free))
+	}
+	if bm, err := readBlockBitmap(file, sb); err != nil {
+		problems = append(problems, fmt.Sprintf("no se pudo leer el bitmap de bloques: %v", err))
+	} else if free := bm.CountFree(); free != sb.SFreeBlocksCount {
+		problems = append(problems, fmt.Sprintf("el superbloque indica %d bloques libres y el bitmap %d", sb.SFreeBlocksCount, free))
+	}
+	if len(problems) == 0 {
+		return line, ""
+	}
+	return line + " *", fmt.Sprintf("* %s: %s (ejecute fsck -id=%s -repair)", mp.ID, strings.Join(problems, "; "), mp.ID)
+}
+
+// duState acumula el recorrido de du
+type duState struct {
+	file    *os.File
+	sb      Superblock
+	human   bool
+	counted map[int32]bool // Inodos ya sumados, para contar una sola vez los enlaces duros
+	lines   []string
+	skipped int
+}
+
+// DU: Suma los bloques asignados a cada carpeta de un subárbol de la partición.
+func du(params map[string]string) string {
+	path, hasPath := params["path"]
+	id, hasID := params["id"]
+	if !hasPath || !hasID {
+		return "Error: Parámetros -id y -path son obligatorios"
+	}
+	_, human := params["h"]
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	pathParts, err := splitPath(path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	index, err := resolvePath(file, sb, pathParts, true)
+	if err != nil {
+		return fmt.Sprintf("Error: %s no encontrado: %v", path, err)
+	}
+	inode, err := readInode(file, sb, index)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", index, err)
+	}
+	if inode.IType == '0' && !hasReadPermission(inode, currentSession) {
+		return fmt.Sprintf("Error: Permiso denegado para leer %s", path)
+	}
+
+	d := &duState{file: file, sb: sb, human: human, counted: map[int32]bool{}}
+	displayPath := "/" + strings.Join(pathParts, "/")
+	total, err := d.walk(index, inode, displayPath)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if inode.IType != '0' {
+		d.lines = append(d.lines, d.format(total, displayPath)) // -path es un archivo
+	}
+
+	salida := strings.Join(d.lines, "\n")
+	if d.skipped > 0 {
+		salida += fmt.Sprintf("\n%d carpeta(s) omitida(s) por falta de permiso de lectura", d.skipped)
+	}
+	return salida
+}
+
+// walk devuelve los bloques asignados a index y su contenido, y agrega una línea por carpeta
+// en orden posterior (primero las subcarpetas), como du
+func (d *duState) walk(index int32, inode Inode, path string) (int64, error) {
+	if d.counted[index] {
+		return 0, nil
+	}
+	d.counted[index] = true
+
+	var total int64
+	for _, blockIndex := range inode.IBlock {
+		if blockIndex != -1 {
+			total++
+		}
+	}
+	if inode.IType != '0' {
+		return total, nil
+	}
+
+	for _, blockIndex := range inode.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(d.file, d.sb, blockIndex)
+		if err != nil {
+			return 0, fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
+		}
+		for _, content := range folderBlock.BContent {
+			name := strings.Trim(string(content.BName[:]), "\x00")
+			if name == "" || name == "." || name == ".." || content.BInode == -1 {
+				continue
+			}
+			child, err := readInode(d.file, d.sb, content.BInode)
+			if err != nil {
+				return 0, fmt.Errorf("error al leer inodo %d: %v", content.BInode, err)
+			}
+			if child.IType == '0' && !hasReadPermission(child, currentSession) {
+				d.skipped++
+				continue
+			}
+			sub, err := d.walk(content.BInode, child, strings.TrimSuffix(path, "/")+"/"+name)
+			if err != nil {
+				return 0, err
+			}
+			total += sub
+		}
+	}
+	d.lines = append(d.lines, d.format(total, path))
+	return total, nil
+}
+
+// format arma una línea de du con el total de bloques de path
+func (d *duState) format(blocks int64, path string) string {
+	bytes := blocks * int64(d.sb.SBlockSize)
+	if d.human {
+		return fmt.Sprintf("%-8s %s", humanSize(bytes), path)
+	}
+	return fmt.Sprintf("%6d bloques %10d bytes  %s", blocks, bytes, path)
+}
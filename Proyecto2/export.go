@@ -0,0 +1,307 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// exportEntry es un elemento de la partición que export escribirá en el anfitrión
+type exportEntry struct {
+	hostPath   string
+	diskPath   string
+	index      int32
+	inode      Inode
+	linkTarget string // Destino de un enlace simbólico, relativo a su carpeta
+}
+
+// hostFileMode convierte los permisos de un inodo (dígitos UGO, p. ej. 664) a bits de modo
+func hostFileMode(perm int32) os.FileMode {
+	owner := (perm / 100) % 10
+	group := (perm / 10) % 10
+	other := perm % 10
+	return os.FileMode(owner&7)<<6 | os.FileMode(group&7)<<3 | os.FileMode(other&7)
+}
+
+// hostDirMode es hostFileMode para carpetas: donde hay lectura agrega ejecución,
+// porque en el anfitrión una carpeta sin x no se puede recorrer
+func hostDirMode(perm int32) os.FileMode {
+	mode := hostFileMode(perm)
+	return mode | (mode&0444)>>2
+}
+
+// inodeTime interpreta una de las fechas de un inodo; ok es false si el campo está vacío o dañado
+func inodeTime(field [19]byte) (time.Time, bool) {
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", strings.Trim(string(field[:]), "\x00"), time.Local)
+	return t, err == nil
+}
+
+// EXPORT: Copia un archivo o una carpeta completa de la partición a una carpeta del anfitrión.
+func export(params map[string]string) string {
+	path, hasPath := params["path"]
+	id, hasID := params["id"]
+	dest, hasDest := params["dest"]
+	if !hasPath || !hasID || !hasDest {
+		return "Error: Parámetros -id, -path y -dest son obligatorios"
+	}
+	_, force := params["force"]
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	hostDir, err := resolveHostPath(dest)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if info, err := os.Stat(hostDir); err == nil && !info.IsDir() {
+		return fmt.Sprintf("Error: El destino %s no es una carpeta", hostDir)
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	// La raíz se exporta directamente en el destino; cualquier otra ruta, con su nombre
+	pathParts, err := splitPath(path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	target := hostDir
+	if len(pathParts) > 0 {
+		target = filepath.Join(hostDir, pathParts[len(pathParts)-1])
+	}
+	rootIndex, err := resolvePath(file, sb, pathParts, true)
+	if err != nil {
+		return fmt.Sprintf("Error: %s no encontrado: %v", path, err)
+	}
+	rootInode, err := readInode(file, sb, rootIndex)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", rootIndex, err)
+	}
+	if !hasReadPermission(rootInode, currentSession) {
+		return fmt.Sprintf("Error: Permiso denegado para leer %s", path)
+	}
+
+	// Reunir primero todo lo que se va a escribir para rechazar conflictos antes de tocar el anfitrión
+	var entries []exportEntry
+	var skipped []string
+	diskRoot := "/" + strings.Join(pathParts, "/")
+	if err := collectExport(file, sb, rootIndex, rootInode, target, strings.TrimSuffix(diskRoot, "/"), &entries, &skipped, map[int32]bool{}); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	// Los enlaces simbólicos se escriben relativos a su carpeta para que en el anfitrión sigan
+	// apuntando a lo exportado; los que apuntan fuera de lo exportado se omiten
+	var outside []string
+	kept := entries[:0]
+	for _, e := range entries {
+		if e.inode.IType == '2' {
+			linkTarget, inside, err := exportLinkTarget(file, sb, e, diskRoot)
+			if err != nil {
+				return fmt.Sprintf("Error: %v", err)
+			}
+			if !inside {
+				outside = append(outside, e.diskPath)
+				continue
+			}
+			e.linkTarget = linkTarget
+		}
+		kept = append(kept, e)
+	}
+	entries = kept
+	if !force {
+		var conflicts []string
+		for _, e := range entries {
+			if e.hostPath == hostDir {
+				continue // La carpeta destino puede existir al exportar la raíz
+			}
+			if _, err := os.Lstat(e.hostPath); err == nil {
+				conflicts = append(conflicts, e.hostPath)
+			}
+		}
+		if len(conflicts) > 0 {
+			return fmt.Sprintf("Error: Ya existen en el anfitrión (use -force para sobrescribir):\n%s", strings.Join(conflicts, "\n"))
+		}
+	}
+
+	if err := os.MkdirAll(hostDir, 0755); err != nil {
+		return fmt.Sprintf("Error al crear %s: %v", hostDir, err)
+	}
+
+	var files, dirs, links int
+	var bytesWritten int64
+	for _, e := range entries {
+		if err := writeExportEntry(file, sb, e); err != nil {
+			return fmt.Sprintf("Error al exportar %s: %v", e.hostPath, err)
+		}
+		switch e.inode.IType {
+		case '0':
+			dirs++
+		case '2':
+			links++
+		default:
+			files++
+			bytesWritten += int64(e.inode.ISize)
+		}
+	}
+
+	// Los permisos y fechas de las carpetas se aplican al final, de las más profundas
+	// a la raíz, para que crear su contenido no cambie la fecha ni falle por permisos
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.inode.IType != '0' {
+			continue
+		}
+		if err := os.Chmod(e.hostPath, hostDirMode(e.inode.IPerm)); err != nil {
+			return fmt.Sprintf("Error al asignar permisos a %s: %v", e.hostPath, err)
+		}
+		if mtime, ok := inodeTime(e.inode.IMtime); ok {
+			os.Chtimes(e.hostPath, mtime, mtime)
+		}
+	}
+
+	var salida strings.Builder
+	salida.WriteString(fmt.Sprintf("Exportado %s a %s: %d archivo(s), %d carpeta(s), %d enlace(s), %d bytes", path, target, files, dirs, links, bytesWritten))
+	for _, s := range skipped {
+		salida.WriteString(fmt.Sprintf("\nOmitido %s: permiso de lectura denegado", s))
+	}
+	for _, s := range outside {
+		salida.WriteString(fmt.Sprintf("\nOmitido %s: el enlace apunta fuera de %s", s, diskRoot))
+	}
+	return salida.String()
+}
+
+// collectExport agrega inodeIndex y, si es carpeta, su contenido a entries en orden previo.
+// Los elementos sin permiso de lectura se omiten y se anotan en skipped.
+func collectExport(file *os.File, sb Superblock, inodeIndex int32, inode Inode, hostPath, diskPath string, entries *[]exportEntry, skipped *[]string, visited map[int32]bool) error {
+	*entries = append(*entries, exportEntry{hostPath: hostPath, diskPath: diskPath, index: inodeIndex, inode: inode})
+	if inode.IType != '0' {
+		return nil
+	}
+	if visited[inodeIndex] {
+		return fmt.Errorf("ciclo detectado en %s (inodo %d)", diskPath, inodeIndex)
+	}
+	visited[inodeIndex] = true
+
+	for _, blockIndex := range inode.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(file, sb, blockIndex)
+		if err != nil {
+			return fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
+		}
+		for _, content := range folderBlock.BContent {
+			name := strings.Trim(string(content.BName[:]), "\x00")
+			if name == "" || name == "." || name == ".." || content.BInode == -1 {
+				continue
+			}
+			child, err := readInode(file, sb, content.BInode)
+			if err != nil {
+				return fmt.Errorf("error al leer inodo %d: %v", content.BInode, err)
+			}
+			childPath := diskPath + "/" + name
+			if child.IType != '2' && !hasReadPermission(child, currentSession) {
+				*skipped = append(*skipped, childPath)
+				continue
+			}
+			if err := collectExport(file, sb, content.BInode, child, filepath.Join(hostPath, name), childPath, entries, skipped, visited); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// exportLinkTarget devuelve el destino del enlace e relativo a la carpeta del enlace, e
+// inside indica si ese destino queda dentro de diskRoot, la ruta exportada
+func exportLinkTarget(file *os.File, sb Superblock, e exportEntry, diskRoot string) (target string, inside bool, err error) {
+	content, err := readFileContent(file, sb, e.inode)
+	if err != nil {
+		return "", false, fmt.Errorf("error al leer el enlace %s: %v", e.diskPath, err)
+	}
+	// Un destino absoluto parte de la raíz de la partición; uno relativo, de la carpeta del enlace
+	target = string(content)
+	if !filepath.IsAbs(target) {
+		target = filepath.Join(filepath.Dir(e.diskPath), target)
+	}
+	target = filepath.Clean(target)
+	if rel, err := filepath.Rel(diskRoot, target); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false, nil
+	}
+	rel, err := filepath.Rel(filepath.Dir(e.diskPath), target)
+	if err != nil {
+		return "", false, err
+	}
+	return rel, true, nil
+}
+
+// writeExportEntry crea en el anfitrión la carpeta, archivo o enlace descrito por e
+func writeExportEntry(file *os.File, sb Superblock, e exportEntry) error {
+	existing, statErr := os.Lstat(e.hostPath)
+	exists := statErr == nil
+
+	if e.inode.IType == '0' {
+		if exists && existing.IsDir() {
+			// Una exportación anterior pudo dejarla sin escritura; sus permisos se reasignan al final
+			return os.Chmod(e.hostPath, 0700)
+		}
+		if exists {
+			if err := os.Remove(e.hostPath); err != nil {
+				return err
+			}
+		}
+		return os.Mkdir(e.hostPath, 0700)
+	}
+
+	// Quitar lo que haya en el anfitrión para no escribir a través de un enlace existente
+	if exists {
+		if existing.IsDir() {
+			return fmt.Errorf("ya existe una carpeta con ese nombre")
+		}
+		if err := os.Remove(e.hostPath); err != nil {
+			return err
+		}
+	}
+
+	if e.inode.IType == '2' {
+		return os.Symlink(e.linkTarget, e.hostPath)
+	}
+	content, err := readFileContent(file, sb, e.inode)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(e.hostPath, content, 0600); err != nil {
+		return err
+	}
+	if err := os.Chmod(e.hostPath, hostFileMode(e.inode.IPerm)); err != nil {
+		return err
+	}
+	if mtime, ok := inodeTime(e.inode.IMtime); ok {
+		if err := os.Chtimes(e.hostPath, mtime, mtime); err != nil {
+			return err
+		}
+	}
+	return nil
+}
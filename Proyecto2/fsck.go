@@ -0,0 +1,470 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+)
+
+// fsckIssue describe un problema encontrado en el sistema de archivos
+type fsckIssue struct {
+	Desc    string
+	Fixable bool
+	Fixed   bool
+}
+
+// fsChecker recorre el árbol de una partición y acumula los problemas encontrados
+type fsChecker struct {
+	file       *os.File
+	sb         Superblock
+	repair     bool
+	inodeBm    *Bitmap
+	blockBm    *Bitmap
+	reached    []bool  // Inodos alcanzables desde la raíz
+	refs       []int32 // Entradas de carpeta que apuntan a cada inodo
+	blockOwner []int32 // Inodo dueño de cada bloque, -1 si ninguno
+	issues     []fsckIssue
+}
+
+// FSCK: Revisa la consistencia del sistema de archivos EXT2 de una partición.
+func fsck(params map[string]string) string {
+	id, hasID := params["id"]
+	if !hasID {
+		return "Error: Parámetro -id es obligatorio"
+	}
+	_, repair := params["repair"]
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, backup, err := loadSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+	partStart, partSize, err := getPartitionBounds(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	c := &fsChecker{
+		file:       file,
+		sb:         sb,
+		repair:     repair,
+		reached:    make([]bool, sb.SInodesCount),
+		refs:       make([]int32, sb.SInodesCount),
+		blockOwner: make([]int32, sb.SBlocksCount),
+	}
+	for i := range c.blockOwner {
+		c.blockOwner[i] = -1
+	}
+	if c.inodeBm, err = readInodeBitmap(file, sb); err != nil {
+		return fmt.Sprintf("Error al leer bitmap de inodos: %v", err)
+	}
+	if c.blockBm, err = readBlockBitmap(file, sb); err != nil {
+		return fmt.Sprintf("Error al leer bitmap de bloques: %v", err)
+	}
+
+	if backup != nil {
+		c.report(fmt.Sprintf("El superbloque principal es inválido; se usó la copia %d (%s)", backup.Slot, backup.Written.Format("2006-01-02 15:04:05")), func() error {
+			return writeSuperblock(file, partStart, &sb)
+		})
+	}
+	c.checkBackups(partStart, partSize)
+
+	c.reached[0] = true
+	if err := c.walk(0, 0, "/"); err != nil {
+		return fmt.Sprintf("Error al recorrer el sistema de archivos: %v", err)
+	}
+	if err := c.checkLinks(); err != nil {
+		return fmt.Sprintf("Error al revisar los enlaces: %v", err)
+	}
+	c.checkBitmaps()
+	if err := c.checkSuperblock(mp); err != nil {
+		return fmt.Sprintf("Error al actualizar el superbloque: %v", err)
+	}
+
+	if len(c.issues) == 0 {
+		return fmt.Sprintf("Sistema de archivos %s sin problemas", id)
+	}
+
+	var salida strings.Builder
+	salida.WriteString(fmt.Sprintf("Sistema de archivos %s: %d problema(s) encontrado(s)", id, len(c.issues)))
+	fixed := 0
+	for _, issue := range c.issues {
+		salida.WriteString("\nProblema: " + issue.Desc)
+		switch {
+		case issue.Fixed:
+			salida.WriteString(" (corregido)")
+			fixed++
+		case issue.Fixable:
+			salida.WriteString(" (corregible con -repair)")
+		}
+	}
+	if repair {
+		// Las copias de respaldo deben reflejar el superbloque ya corregido; se reescribe la
+		// más antigua para conservar la anterior
+		if fixed > 0 && hasBackupArea(sb, partStart, partSize) {
+			if sb, err = readSuperblock(file, mp); err == nil {
+				_, err = updateBackupSuperblock(file, partStart, partSize, &sb)
+			}
+			if err != nil {
+				return fmt.Sprintf("Error al actualizar las copias del superbloque: %v", err)
+			}
+		}
+		if err := file.Sync(); err != nil {
+			return fmt.Sprintf("Error syncing disk: %v", err)
+		}
+		salida.WriteString(fmt.Sprintf("\nSe corrigieron %d problema(s)", fixed))
+	}
+	return salida.String()
+}
+
+// report registra un problema; fix se ejecuta solo si se pidió -repair
+func (c *fsChecker) report(desc string, fix func() error) {
+	issue := fsckIssue{Desc: desc, Fixable: fix != nil}
+	if fix != nil && c.repair {
+		if err := fix(); err != nil {
+			issue.Desc += fmt.Sprintf(" (error al corregir: %v)", err)
+		} else {
+			issue.Fixed = true
+		}
+	}
+	c.issues = append(c.issues, issue)
+}
+
+// walk revisa el inodo index (alcanzado desde parent) y, si es carpeta, sus entradas
+func (c *fsChecker) walk(index, parent int32, path string) error {
+	inode, err := readInode(c.file, c.sb, index)
+	if err != nil {
+		return err
+	}
+	if inode.IType != '0' && inode.IType != '1' && inode.IType != '2' {
+		c.report(fmt.Sprintf("%s (inodo %d) tiene un tipo inválido (%q)", path, index, inode.IType), nil)
+		return nil
+	}
+
+	c.checkPointers(index, &inode, path)
+	if inode.IType != '0' {
+		// Los enlaces simbólicos guardan la ruta destino como el contenido de un archivo
+		c.checkFileSize(index, &inode, path)
+		return nil
+	}
+
+	var children []FolderContent
+	var childPaths []string
+	seen := map[int32]bool{}
+	for k, blockIndex := range inode.IBlock {
+		// Un bloque repetido en el mismo inodo (punteros antiguos a 0) se revisa una sola vez
+		if !c.owns(index, blockIndex) || seen[blockIndex] {
+			continue
+		}
+		seen[blockIndex] = true
+		folderBlock, err := readFolderBlock(c.file, c.sb, blockIndex)
+		if err != nil {
+			return err
+		}
+		dirty := false
+		for j := range folderBlock.BContent {
+			entry := &folderBlock.BContent[j]
+			name := strings.Trim(string(entry.BName[:]), "\x00")
+			if name == "" {
+				continue
+			}
+			// . y .. solo tienen sentido en el primer bloque de la carpeta
+			if (name == "." || name == "..") && k == 0 {
+				want := index
+				if name == ".." {
+					want = parent
+				}
+				if entry.BInode != want {
+					c.report(fmt.Sprintf("La entrada %s de %s apunta al inodo %d en lugar de %d", name, path, entry.BInode, want), func() error {
+						entry.BInode = want
+						dirty = true
+						return nil
+					})
+				}
+				continue
+			}
+
+			childPath := strings.TrimSuffix(path, "/") + "/" + name
+			if entry.BInode < 0 || entry.BInode >= c.sb.SInodesCount || !c.inodeBm.Get(entry.BInode) {
+				c.report(fmt.Sprintf("%s apunta al inodo %d, que no está en uso", childPath, entry.BInode), func() error {
+					*entry = FolderContent{BInode: -1}
+					dirty = true
+					return nil
+				})
+				continue
+			}
+			child, err := readInode(c.file, c.sb, entry.BInode)
+			if err != nil {
+				return err
+			}
+			if child.IType == '0' && c.reached[entry.BInode] {
+				c.report(fmt.Sprintf("La carpeta %s (inodo %d) está referenciada más de una vez", childPath, entry.BInode), func() error {
+					*entry = FolderContent{BInode: -1}
+					dirty = true
+					return nil
+				})
+				continue
+			}
+			c.refs[entry.BInode]++
+			if !c.reached[entry.BInode] {
+				c.reached[entry.BInode] = true
+				children = append(children, *entry)
+				childPaths = append(childPaths, childPath)
+			}
+		}
+		if dirty {
+			if err := writeFolderBlock(c.file, c.sb, blockIndex, &folderBlock); err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, child := range children {
+		if err := c.walk(child.BInode, index, childPaths[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPointers valida los punteros a bloques de un inodo y registra a su dueño
+func (c *fsChecker) checkPointers(index int32, inode *Inode, path string) {
+	var legacy []int
+	for k, blockIndex := range inode.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		dropPointer := func() error {
+			inode.IBlock[k] = -1
+			return writeInode(c.file, c.sb, index, inode)
+		}
+		switch {
+		case blockIndex == 0 && (index != 0 || k > 0):
+			// Los inodos del formato anterior usaban 0 en lugar de -1 para punteros vacíos
+			legacy = append(legacy, k)
+		case blockIndex < 0 || blockIndex >= c.sb.SBlocksCount:
+			c.report(fmt.Sprintf("%s (inodo %d) apunta al bloque inexistente %d", path, index, blockIndex), dropPointer)
+		case c.blockOwner[blockIndex] != -1:
+			c.report(fmt.Sprintf("El bloque %d está referenciado por los inodos %d y %d (%s)", blockIndex, c.blockOwner[blockIndex], index, path), dropPointer)
+		default:
+			c.blockOwner[blockIndex] = index
+		}
+	}
+	if len(legacy) > 0 {
+		c.report(fmt.Sprintf("%s (inodo %d) usa el bloque 0 de la raíz como puntero vacío en %d entrada(s)", path, index, len(legacy)), func() error {
+			for _, k := range legacy {
+				inode.IBlock[k] = -1
+			}
+			return writeInode(c.file, c.sb, index, inode)
+		})
+	}
+}
+
+// owns indica si blockIndex es un puntero válido que pertenece al inodo index
+func (c *fsChecker) owns(index, blockIndex int32) bool {
+	return blockIndex >= 0 && blockIndex < c.sb.SBlocksCount && c.blockOwner[blockIndex] == index
+}
+
+// checkFileSize compara el tamaño de un archivo con los bloques que tiene asignados
+func (c *fsChecker) checkFileSize(index int32, inode *Inode, path string) {
+	var blocks []int
+	for k, blockIndex := range inode.IBlock {
+		if c.owns(index, blockIndex) {
+			blocks = append(blocks, k)
+		}
+	}
+	need := int(math.Ceil(float64(inode.ISize) / float64(c.sb.SBlockSize)))
+	capacity := int32(len(blocks)) * c.sb.SBlockSize
+	switch {
+	case inode.ISize < 0 || inode.ISize > capacity:
+		c.report(fmt.Sprintf("%s (inodo %d) tiene tamaño %d pero solo %d bloque(s) asignados", path, index, inode.ISize, len(blocks)), func() error {
+			inode.ISize = capacity
+			return writeInode(c.file, c.sb, index, inode)
+		})
+	case len(blocks) > need:
+		c.report(fmt.Sprintf("%s (inodo %d) tiene %d bloque(s) asignados pero su tamaño (%d) solo requiere %d", path, index, len(blocks), inode.ISize, need), func() error {
+			for _, k := range blocks[need:] {
+				c.blockOwner[inode.IBlock[k]] = -1
+				inode.IBlock[k] = -1
+			}
+			return writeInode(c.file, c.sb, index, inode)
+		})
+	}
+}
+
+// checkLinks compara el contador de enlaces de cada archivo con las entradas que lo referencian
+func (c *fsChecker) checkLinks() error {
+	for i, n := range c.refs {
+		if n == 0 || !c.reached[i] {
+			continue
+		}
+		index := int32(i)
+		inode, err := readInode(c.file, c.sb, index)
+		if err != nil {
+			return err
+		}
+		if inode.IType == '0' || linkCount(inode) == n {
+			continue
+		}
+		desc := fmt.Sprintf("El inodo %d tiene %d enlace(s) registrados pero %d entrada(s) de carpeta lo referencian", index, linkCount(inode), n)
+		if !hasLinkCounts(c.sb) {
+			c.report(desc, nil) // El formato anterior no tiene dónde guardar el contador
+			continue
+		}
+		c.report(desc, func() error {
+			inode.ILinks = n
+			return writeInode(c.file, c.sb, index, &inode)
+		})
+	}
+	return nil
+}
+
+// checkBitmaps compara los bitmaps con los inodos y bloques realmente alcanzables
+func (c *fsChecker) checkBitmaps() {
+	var orphanInodes, unmarkedInodes, orphanBlocks, unmarkedBlocks []int32
+	for i := int32(0); i < c.sb.SInodesCount; i++ {
+		if c.inodeBm.Get(i) && !c.reached[i] {
+			orphanInodes = append(orphanInodes, i)
+		} else if !c.inodeBm.Get(i) && c.reached[i] {
+			unmarkedInodes = append(unmarkedInodes, i)
+		}
+	}
+	for i := int32(0); i < c.sb.SBlocksCount; i++ {
+		// Los bloques reservados por mkfs siempre se consideran en uso
+		expected := c.blockOwner[i] != -1 || (i >= 2 && i < 2+c.sb.SReservedBlocks)
+		if c.blockBm.Get(i) && !expected {
+			orphanBlocks = append(orphanBlocks, i)
+		} else if !c.blockBm.Get(i) && expected {
+			unmarkedBlocks = append(unmarkedBlocks, i)
+		}
+	}
+
+	writeInodes := func(set bool, list []int32) func() error {
+		return func() error {
+			for _, i := range list {
+				if set {
+					c.inodeBm.Set(i)
+				} else {
+					c.inodeBm.Clear(i)
+				}
+			}
+			return c.inodeBm.write(c.file)
+		}
+	}
+	writeBlocks := func(set bool, list []int32) func() error {
+		return func() error {
+			for _, i := range list {
+				if set {
+					c.blockBm.Set(i)
+				} else {
+					c.blockBm.Clear(i)
+				}
+			}
+			return c.blockBm.write(c.file)
+		}
+	}
+	if len(orphanInodes) > 0 {
+		c.report(fmt.Sprintf("%d inodo(s) huérfanos marcados en uso sin estar enlazados: %s", len(orphanInodes), indexList(orphanInodes)), writeInodes(false, orphanInodes))
+	}
+	if len(unmarkedInodes) > 0 {
+		c.report(fmt.Sprintf("%d inodo(s) en uso marcados como libres en el bitmap: %s", len(unmarkedInodes), indexList(unmarkedInodes)), writeInodes(true, unmarkedInodes))
+	}
+	if len(orphanBlocks) > 0 {
+		c.report(fmt.Sprintf("%d bloque(s) marcados en uso sin que ningún inodo los referencie: %s", len(orphanBlocks), indexList(orphanBlocks)), writeBlocks(false, orphanBlocks))
+	}
+	if len(unmarkedBlocks) > 0 {
+		c.report(fmt.Sprintf("%d bloque(s) en uso marcados como libres en el bitmap: %s", len(unmarkedBlocks), indexList(unmarkedBlocks)), writeBlocks(true, unmarkedBlocks))
+	}
+}
+
+// checkBackups verifica que las copias de respaldo del superbloque sean legibles
+func (c *fsChecker) checkBackups(partStart, partSize int32) {
+	if !hasBackupArea(c.sb, partStart, partSize) {
+		return // Formato anterior a las copias de respaldo
+	}
+	valid := map[int]bool{}
+	for _, b := range readBackupSuperblocks(c.file, partStart, partSize) {
+		valid[b.Slot] = true
+	}
+	for k := 1; k <= sbBackupCount; k++ {
+		if !valid[k] {
+			c.report(fmt.Sprintf("La copia %d del superbloque falta o no es válida", k), func() error {
+				return nil // Se reescribe al terminar la reparación
+			})
+		}
+	}
+}
+
+// checkSuperblock compara los contadores del superbloque con los bitmaps
+func (c *fsChecker) checkSuperblock(mp *MountedPartition) error {
+	partStart, _, err := getPartitionBounds(c.file, mp)
+	if err != nil {
+		return err
+	}
+	sb := c.sb
+	dirty := false
+	if free := c.inodeBm.CountFree(); free != sb.SFreeInodesCount {
+		c.report(fmt.Sprintf("El superbloque indica %d inodos libres, el bitmap tiene %d", sb.SFreeInodesCount, free), func() error {
+			sb.SFreeInodesCount = free
+			dirty = true
+			return nil
+		})
+	}
+	if free := c.blockBm.CountFree(); free != sb.SFreeBlocksCount {
+		c.report(fmt.Sprintf("El superbloque indica %d bloques libres, el bitmap tiene %d", sb.SFreeBlocksCount, free), func() error {
+			sb.SFreeBlocksCount = free
+			dirty = true
+			return nil
+		})
+	}
+	if sb.SAllocFit == allocFirstFit {
+		if first := search(c.inodeBm, 0); first != -1 && first < sb.SFirstIno {
+			c.report(fmt.Sprintf("SFirstIno es %d pero el primer inodo libre es %d", sb.SFirstIno, first), func() error {
+				sb.SFirstIno = first
+				dirty = true
+				return nil
+			})
+		}
+		if first := search(c.blockBm, 0); first != -1 && first < sb.SFirstBlo {
+			c.report(fmt.Sprintf("SFirstBlo es %d pero el primer bloque libre es %d", sb.SFirstBlo, first), func() error {
+				sb.SFirstBlo = first
+				dirty = true
+				return nil
+			})
+		}
+	}
+	if dirty {
+		return writeSuperblock(c.file, partStart, &sb)
+	}
+	return nil
+}
+
+// indexList resume una lista de índices mostrando como máximo los primeros 10
+func indexList(list []int32) string {
+	var parts []string
+	for i, v := range list {
+		if i == 10 {
+			parts = append(parts, "...")
+			break
+		}
+		parts = append(parts, fmt.Sprint(v))
+	}
+	return strings.Join(parts, ", ")
+}
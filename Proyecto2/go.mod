@@ -2,4 +2,4 @@ module proyecto2
 
 go 1.22.1
 
-require github.com/rs/cors v1.11.1 // indirect
+require github.com/rs/cors v1.11.1
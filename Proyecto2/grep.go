@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// grepState reúne lo necesario para buscar texto dentro de los archivos de una partición
+type grepState struct {
+	file    *os.File
+	sb      Superblock
+	match   func(line string) bool
+	results []string
+	skipped int // Archivos y carpetas sin permiso de lectura
+}
+
+// GREP: Busca un texto o expresión regular dentro de un archivo o, con -r, de una carpeta.
+func grep(params map[string]string) string {
+	path, hasPath := params["path"]
+	id, hasID := params["id"]
+	pattern, hasPattern := params["pattern"]
+	if !hasPath || !hasID || !hasPattern {
+		return "Error: Parámetros -id, -path y -pattern son obligatorios"
+	}
+	pattern = strings.Trim(pattern, "\"")
+	if pattern == "" {
+		return "Error: El patrón no puede estar vacío"
+	}
+	_, recursive := params["r"]
+	_, ignoreCase := params["i"]
+	_, useRegex := params["regex"]
+
+	// Preparar la función de coincidencia
+	var match func(line string) bool
+	if useRegex {
+		expr := pattern
+		if ignoreCase {
+			expr = "(?i)" + expr
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return fmt.Sprintf("Error: Expresión regular no válida: %v", err)
+		}
+		match = re.MatchString
+	} else if ignoreCase {
+		lower := strings.ToLower(pattern)
+		match = func(line string) bool { return strings.Contains(strings.ToLower(line), lower) }
+	} else {
+		match = func(line string) bool { return strings.Contains(line, pattern) }
+	}
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	pathParts, err := splitPath(path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	index, err := resolvePath(file, sb, pathParts, true)
+	if err != nil {
+		return fmt.Sprintf("Error: %s no encontrado: %v", path, err)
+	}
+	inode, err := readInode(file, sb, index)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", index, err)
+	}
+	if inode.IType == '0' && !recursive {
+		return fmt.Sprintf("Error: %s es una carpeta (use -r para buscar en su contenido)", path)
+	}
+	if !hasReadPermission(inode, currentSession) {
+		return fmt.Sprintf("Error: Permiso denegado para leer %s", path)
+	}
+
+	g := &grepState{file: file, sb: sb, match: match}
+	displayPath := "/" + strings.Join(pathParts, "/")
+	if inode.IType == '0' {
+		err = g.searchFolder(index, inode, strings.TrimSuffix(displayPath, "/"), map[int32]bool{})
+	} else {
+		err = g.searchFile(index, inode, displayPath)
+	}
+	if err != nil {
+		return fmt.Sprintf("Error durante la búsqueda: %v", err)
+	}
+
+	var salida strings.Builder
+	if len(g.results) == 0 {
+		salida.WriteString(fmt.Sprintf("No se encontraron coincidencias para %s", pattern))
+	} else {
+		salida.WriteString(strings.Join(g.results, "\n"))
+	}
+	if g.skipped > 0 {
+		salida.WriteString(fmt.Sprintf("\n%d elemento(s) omitido(s) por falta de permiso de lectura", g.skipped))
+	}
+	return salida.String()
+}
+
+// searchFile agrega las líneas de inode que coinciden, con el formato ruta:línea:texto.
+// Solo los archivos con coincidencias registran la fecha de acceso.
+func (g *grepState) searchFile(index int32, inode Inode, path string) error {
+	content, err := readFileContent(g.file, g.sb, inode)
+	if err != nil {
+		return fmt.Errorf("error al leer %s: %v", path, err)
+	}
+	matched := false
+	if bytes.IndexByte(content, 0) != -1 {
+		// Igual que grep, un archivo con bytes nulos se trata como binario y no se imprime
+		if matched = g.match(string(content)); matched {
+			g.results = append(g.results, fmt.Sprintf("Archivo binario %s coincide", path))
+		}
+	} else {
+		for n, line := range strings.Split(string(content), "\n") {
+			line = strings.TrimSuffix(line, "\r")
+			if g.match(line) {
+				g.results = append(g.results, fmt.Sprintf("%s:%d:%s", path, n+1, line))
+				matched = true
+			}
+		}
+	}
+	if !matched {
+		return nil
+	}
+	inode.IAtime = timestamp()
+	if err = writeInode(g.file, g.sb, index, &inode); err != nil {
+		return fmt.Errorf("error al escribir inodo %d: %v", index, err)
+	}
+	return nil
+}
+
+// searchFolder busca en los archivos de la carpeta y sus subcarpetas. Los enlaces
+// simbólicos no se siguen y visited evita recorrer dos veces una carpeta.
+func (g *grepState) searchFolder(index int32, inode Inode, path string, visited map[int32]bool) error {
+	if visited[index] {
+		return fmt.Errorf("ciclo detectado en %s (inodo %d)", path, index)
+	}
+	visited[index] = true
+
+	for _, blockIndex := range inode.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(g.file, g.sb, blockIndex)
+		if err != nil {
+			return fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
+		}
+		for _, content := range folderBlock.BContent {
+			name := strings.Trim(string(content.BName[:]), "\x00")
+			if name == "" || name == "." || name == ".." || content.BInode == -1 {
+				continue
+			}
+			child, err := readInode(g.file, g.sb, content.BInode)
+			if err != nil {
+				return fmt.Errorf("error al leer inodo %d: %v", content.BInode, err)
+			}
+			if child.IType == '2' {
+				continue
+			}
+			if !hasReadPermission(child, currentSession) {
+				g.skipped++
+				continue
+			}
+			childPath := path + "/" + name
+			if child.IType == '0' {
+				err = g.searchFolder(content.BInode, child, childPath, visited)
+			} else {
+				err = g.searchFile(content.BInode, child, childPath)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// dataRoot es la carpeta del servidor desde la que los comandos pueden leer o escribir
+// archivos del sistema anfitrión. Se configura con la variable de entorno MIA_DATA_ROOT
+// y por defecto es el directorio de trabajo del servidor.
+var dataRoot = func() string {
+	if root := os.Getenv("MIA_DATA_ROOT"); root != "" {
+		return root
+	}
+	if wd, err := os.Getwd(); err == nil {
+		return wd
+	}
+	return "."
+}()
+
+// resolveHostPath convierte una ruta del anfitrión en una ruta absoluta dentro de dataRoot.
+// Las rutas relativas se toman desde dataRoot; las que salen de ella se rechazan.
+func resolveHostPath(path string) (string, error) {
+	path = strings.Trim(path, "\"")
+	if path == "" {
+		return "", fmt.Errorf("ruta del anfitrión vacía")
+	}
+	root, err := filepath.Abs(dataRoot)
+	if err != nil {
+		return "", fmt.Errorf("carpeta de datos inválida: %v", err)
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+
+	// Resolver enlaces del anfitrión para que no permitan salir de la carpeta de datos. Si la
+	// ruta aún no existe se resuelve su ancestro existente más profundo y se le agrega el resto,
+	// así un destino nuevo tampoco sale por una carpeta padre que sea enlace.
+	rest := ""
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if real, err := filepath.EvalSymlinks(dir); err == nil {
+			path = filepath.Join(real, rest)
+			break
+		}
+		if filepath.Dir(dir) == dir {
+			break
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+	if realRoot, err := filepath.EvalSymlinks(root); err == nil {
+		root = realRoot
+	}
+	if rel, err := filepath.Rel(root, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("la ruta %s está fuera de la carpeta de datos %s", path, root)
+	}
+	return path, nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// importer lleva el estado de un import: la partición destino y lo creado hasta el momento
+type importer struct {
+	file    *os.File
+	mp      *MountedPartition
+	sb      Superblock
+	files   int
+	dirs    int
+	links   int
+	bytes   int64
+	skipped []string
+}
+
+// inodePerm convierte los bits de permiso del anfitrión al formato UGO de IPerm (p. ej. 0644 -> 644)
+func inodePerm(mode os.FileMode) int32 {
+	perm := int32(mode.Perm())
+	return (perm>>6&7)*100 + (perm>>3&7)*10 + perm&7
+}
+
+// IMPORT: Copia recursivamente un archivo o carpeta del anfitrión dentro de una carpeta de la partición.
+func importFiles(params map[string]string) string {
+	src, hasSrc := params["src"]
+	id, hasID := params["id"]
+	dest, hasDest := params["dest"]
+	if !hasSrc || !hasID || !hasDest {
+		return "Error: Parámetros -id, -src y -dest son obligatorios"
+	}
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	hostPath, err := resolveHostPath(src)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	info, err := os.Lstat(hostPath)
+	if err != nil {
+		return fmt.Sprintf("Error: No se puede leer %s: %v", hostPath, err)
+	}
+	// El nombre se toma de la ruta indicada y no de la resuelta, así un enlace del anfitrión
+	// se importa con su propio nombre y no con el de su destino
+	name := filepath.Base(filepath.Clean(strings.Trim(src, "\"")))
+	if name == "." || name == ".." || name == string(filepath.Separator) {
+		name = filepath.Base(hostPath)
+	}
+	if len(name) > 12 {
+		return fmt.Sprintf("Error: El nombre %s excede 12 caracteres", name)
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	// Buscar la carpeta destino
+	destParts, err := splitPath(dest)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	destIndex, err := resolvePath(file, sb, destParts, true)
+	if err != nil {
+		return fmt.Sprintf("Error: %s no encontrado: %v", dest, err)
+	}
+	destInode, err := readInode(file, sb, destIndex)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", destIndex, err)
+	}
+	if destInode.IType != '0' {
+		return fmt.Sprintf("Error: %s no es una carpeta", dest)
+	}
+	if !hasWritePermission(destInode, currentSession.UserID, currentSession.GroupID) {
+		return fmt.Sprintf("Error: Permisos insuficientes para escribir en %s", dest)
+	}
+	if existing, err := lookupEntry(file, sb, destIndex, name); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	} else if existing != -1 {
+		return fmt.Sprintf("Error: %s ya existe en %s", name, dest)
+	}
+
+	im := &importer{file: file, mp: mp, sb: sb}
+	freeInodes, freeBlocks := sb.SFreeInodesCount, sb.SFreeBlocksCount
+	diskPath := strings.TrimSuffix("/"+strings.Join(destParts, "/"), "/") + "/" + name
+	importErr := im.walk(hostPath, info, destIndex, diskPath)
+	if err := file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+
+	var salida strings.Builder
+	if importErr != nil {
+		salida.WriteString(fmt.Sprintf("Error: Importación detenida: %v\n", importErr))
+		salida.WriteString("Se conserva lo importado hasta ese punto\n")
+	}
+	salida.WriteString(fmt.Sprintf("Importado %s en %s: %d archivo(s), %d carpeta(s), %d enlace(s), %d bytes",
+		hostPath, diskPath, im.files, im.dirs, im.links, im.bytes))
+	salida.WriteString(fmt.Sprintf("\nInodos usados: %d, bloques usados: %d",
+		freeInodes-im.sb.SFreeInodesCount, freeBlocks-im.sb.SFreeBlocksCount))
+	for _, s := range im.skipped {
+		salida.WriteString("\nOmitido " + s)
+	}
+	return salida.String()
+}
+
+// walk crea en parentIndex el elemento del anfitrión hostPath y, si es carpeta, su contenido.
+// Los elementos que no se pueden representar se omiten; cualquier otro error detiene el recorrido.
+func (im *importer) walk(hostPath string, info os.FileInfo, parentIndex int32, diskPath string) error {
+	name := path.Base(diskPath)
+	if diskFile, err := filepath.Abs(im.mp.Path); err == nil && hostPath == diskFile {
+		im.skipped = append(im.skipped, fmt.Sprintf("%s: es el disco de la partición destino", hostPath))
+		return nil
+	}
+
+	switch {
+	case info.IsDir():
+		index, err := im.add(parentIndex, name, func(alloc *Allocator) (int32, error) {
+			return writeNewFolder(im.file, im.sb, alloc, parentIndex, inodePerm(info.Mode()))
+		})
+		if err != nil {
+			return fmt.Errorf("%s: %v", diskPath, err)
+		}
+		im.dirs++
+
+		entries, err := os.ReadDir(hostPath)
+		if err != nil {
+			return fmt.Errorf("no se puede leer %s: %v", hostPath, err)
+		}
+		for _, entry := range entries {
+			childPath := filepath.Join(hostPath, entry.Name())
+			if len(entry.Name()) > 12 {
+				im.skipped = append(im.skipped, fmt.Sprintf("%s: el nombre excede 12 caracteres", childPath))
+				continue
+			}
+			childInfo, err := entry.Info()
+			if err != nil {
+				return fmt.Errorf("no se puede leer %s: %v", childPath, err)
+			}
+			if err := im.walk(childPath, childInfo, index, diskPath+"/"+entry.Name()); err != nil {
+				return err
+			}
+		}
+
+	case info.Mode()&os.ModeSymlink != 0:
+		// El destino se guarda como texto y no se sigue, igual que ln -s
+		target, err := os.Readlink(hostPath)
+		if err != nil {
+			return fmt.Errorf("no se puede leer %s: %v", hostPath, err)
+		}
+		if _, err := im.add(parentIndex, name, func(alloc *Allocator) (int32, error) {
+			return writeNewInode(im.file, im.sb, alloc, '2', 777, []byte(target))
+		}); err != nil {
+			return fmt.Errorf("%s: %v", diskPath, err)
+		}
+		im.links++
+
+	case info.Mode().IsRegular():
+		maxSize := int64(len(Inode{}.IBlock)) * int64(im.sb.SBlockSize)
+		if info.Size() > maxSize {
+			im.skipped = append(im.skipped, fmt.Sprintf("%s: %d bytes excede el máximo de %d por archivo", hostPath, info.Size(), maxSize))
+			return nil
+		}
+		content, err := os.ReadFile(hostPath)
+		if err != nil {
+			return fmt.Errorf("no se puede leer %s: %v", hostPath, err)
+		}
+		if _, err := im.add(parentIndex, name, func(alloc *Allocator) (int32, error) {
+			return writeNewInode(im.file, im.sb, alloc, '1', inodePerm(info.Mode()), content)
+		}); err != nil {
+			return fmt.Errorf("%s: %v", diskPath, err)
+		}
+		im.files++
+		im.bytes += int64(len(content))
+
+	default:
+		im.skipped = append(im.skipped, fmt.Sprintf("%s: tipo de archivo no soportado", hostPath))
+	}
+	return nil
+}
+
+// add crea un inodo con create y lo enlaza como name en parentIndex en una sola transacción,
+// de modo que si falta espacio la partición queda como antes de este elemento
+func (im *importer) add(parentIndex int32, name string, create func(alloc *Allocator) (int32, error)) (int32, error) {
+	alloc, err := newAllocator(im.file, im.mp, &im.sb)
+	if err != nil {
+		return -1, err
+	}
+	index, err := create(alloc)
+	if err != nil {
+		alloc.Rollback()
+		return -1, err
+	}
+	if err := addFolderEntry(im.file, im.sb, alloc, parentIndex, name, index); err != nil {
+		alloc.Rollback()
+		return -1, err
+	}
+	if err := alloc.Commit(); err != nil {
+		return -1, err
+	}
+	return index, nil
+}
@@ -0,0 +1,243 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxSymlinkHops limita cuántos enlaces simbólicos se siguen al resolver una ruta,
+// de modo que un ciclo de enlaces produce un error en lugar de recorrerse para siempre
+const maxSymlinkHops = 8
+
+// linkCount devuelve cuántas entradas de carpeta apuntan a un inodo.
+// Los inodos de formatos anteriores no guardan el contador y tienen un solo enlace.
+func linkCount(inode Inode) int32 {
+	if inode.ILinks <= 0 {
+		return 1
+	}
+	return inode.ILinks
+}
+
+// hasLinkCounts indica si los inodos de la partición tienen espacio para ILinks
+func hasLinkCounts(sb Superblock) bool {
+	return sb.SInodeSize >= int32(binary.Size(Inode{}))
+}
+
+// lookupEntry busca name en la carpeta dirIndex y devuelve su inodo, o -1 si no existe
+func lookupEntry(file *os.File, sb Superblock, dirIndex int32, name string) (int32, error) {
+	dir, err := readInode(file, sb, dirIndex)
+	if err != nil {
+		return -1, fmt.Errorf("error al leer inodo %d: %v", dirIndex, err)
+	}
+	if dir.IType != '0' {
+		return -1, fmt.Errorf("el inodo %d no es una carpeta", dirIndex)
+	}
+	for _, blockIndex := range dir.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(file, sb, blockIndex)
+		if err != nil {
+			return -1, fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
+		}
+		for _, content := range folderBlock.BContent {
+			if strings.Trim(string(content.BName[:]), "\x00") == name {
+				return content.BInode, nil
+			}
+		}
+	}
+	return -1, nil
+}
+
+// readSymlinkTarget devuelve la ruta guardada en un enlace simbólico
+func readSymlinkTarget(file *os.File, sb Superblock, inode Inode) (string, error) {
+	content, err := readFileContent(file, sb, inode)
+	return string(content), err
+}
+
+// resolvePath recorre pathParts desde la raíz siguiendo los enlaces simbólicos
+// intermedios, y el último componente solo si followLast es true
+func resolvePath(file *os.File, sb Superblock, pathParts []string, followLast bool) (int32, error) {
+	current := int32(0) // Inodo raíz
+	remaining := append([]string(nil), pathParts...)
+	hops := 0
+	for len(remaining) > 0 {
+		part := remaining[0]
+		remaining = remaining[1:]
+		if len(part) > 12 {
+			return 0, fmt.Errorf("el nombre %s excede 12 caracteres", part)
+		}
+
+		inode, err := readInode(file, sb, current)
+		if err != nil {
+			return 0, fmt.Errorf("error al leer inodo %d: %v", current, err)
+		}
+		if inode.IType != '0' {
+			return 0, fmt.Errorf("%s no es una carpeta", part)
+		}
+		child, err := lookupEntry(file, sb, current, part)
+		if err != nil {
+			return 0, err
+		}
+		if child == -1 {
+			if len(remaining) > 0 {
+				return 0, fmt.Errorf("la carpeta %s no existe", part)
+			}
+			return 0, fmt.Errorf("%s no existe", part)
+		}
+
+		childInode, err := readInode(file, sb, child)
+		if err != nil {
+			return 0, fmt.Errorf("error al leer inodo %d: %v", child, err)
+		}
+		if childInode.IType == '2' && (len(remaining) > 0 || followLast) {
+			hops++
+			if hops > maxSymlinkHops {
+				return 0, fmt.Errorf("demasiados niveles de enlaces simbólicos en %s (posible ciclo)", part)
+			}
+			target, err := readSymlinkTarget(file, sb, childInode)
+			if err != nil {
+				return 0, err
+			}
+			// Un destino absoluto se resuelve desde la raíz; uno relativo, desde la carpeta del enlace
+			if strings.HasPrefix(target, "/") {
+				current = 0
+			}
+			var targetParts []string
+			for _, p := range strings.Split(target, "/") {
+				if p = strings.TrimSpace(p); p != "" && p != "." {
+					targetParts = append(targetParts, p)
+				}
+			}
+			remaining = append(targetParts, remaining...)
+			continue
+		}
+		current = child
+	}
+	return current, nil
+}
+
+// LN: Crea un enlace duro o, con -s, un enlace simbólico.
+func ln(params map[string]string) string {
+	target, hasTarget := params["target"]
+	link, hasLink := params["link"]
+	id, hasID := params["id"]
+	if !hasTarget || !hasLink || !hasID {
+		return "Error: Parámetros -id, -target y -link son obligatorios"
+	}
+	_, symbolic := params["s"]
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	linkParts, err := normalizePath(link)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	linkName := linkParts[len(linkParts)-1]
+	parentIndex, err := navigateToParent(file, sb, linkParts[:len(linkParts)-1])
+	if err != nil {
+		return fmt.Sprintf("Error al navegar a la carpeta padre: %v", err)
+	}
+	parentInode, err := readInode(file, sb, parentIndex)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo padre: %v", err)
+	}
+	if parentInode.IType != '0' {
+		return fmt.Sprintf("Error: La carpeta padre de %s no es una carpeta", link)
+	}
+	if !hasWritePermission(parentInode, currentSession.UserID, currentSession.GroupID) {
+		return "Error: Permisos insuficientes para crear el enlace en la carpeta padre"
+	}
+	if existing, err := lookupEntry(file, sb, parentIndex, linkName); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	} else if existing != -1 {
+		return fmt.Sprintf("Error: %s ya existe", link)
+	}
+
+	alloc, err := newAllocator(file, mp, &sb)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	var linkIndex int32
+	var hardLinked *Inode // Inodo destino de un enlace duro, para sumarle el enlace
+	if symbolic {
+		// El destino se guarda tal cual; puede no existir todavía
+		target = strings.Trim(target, "\"")
+		if target == "" {
+			return "Error: El destino del enlace no puede estar vacío"
+		}
+		if linkIndex, err = writeNewInode(file, sb, alloc, '2', 777, []byte(target)); err != nil {
+			alloc.Rollback()
+			return fmt.Sprintf("Error: %v", err)
+		}
+	} else {
+		targetParts, err := normalizePath(target)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if linkIndex, err = resolvePath(file, sb, targetParts, false); err != nil {
+			return fmt.Sprintf("Error al buscar %s: %v", target, err)
+		}
+		inode, err := readInode(file, sb, linkIndex)
+		if err != nil {
+			return fmt.Sprintf("Error al leer inodo %d: %v", linkIndex, err)
+		}
+		if inode.IType == '0' {
+			return fmt.Sprintf("Error: No se permiten enlaces duros a carpetas (%s)", target)
+		}
+		if !hasLinkCounts(sb) {
+			return "Error: El formato de esta partición no guarda contadores de enlaces; use -s para un enlace simbólico"
+		}
+		hardLinked = &inode
+	}
+
+	if err := addFolderEntry(file, sb, alloc, parentIndex, linkName, linkIndex); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if hardLinked != nil {
+		hardLinked.ILinks = linkCount(*hardLinked) + 1
+		if err := writeInode(file, sb, linkIndex, hardLinked); err != nil {
+			alloc.Rollback()
+			return fmt.Sprintf("Error al escribir inodo %d: %v", linkIndex, err)
+		}
+	}
+	if err := alloc.Commit(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+
+	if symbolic {
+		return fmt.Sprintf("Enlace simbólico %s -> %s creado exitosamente", link, target)
+	}
+	return fmt.Sprintf("Enlace duro %s -> %s creado exitosamente", link, target)
+}
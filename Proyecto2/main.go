@@ -1,9 +1,11 @@
 package main
 
 import (
+	"encoding/base64"
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	"math"
 	"math/rand"
 	"net/http"
 	"os"
@@ -65,6 +67,7 @@ func main() {
 	mux := http.NewServeMux()
 	mux.HandleFunc("/partitions", manejarParticiones)
 	mux.HandleFunc("/execute", manejarEjecucion)
+	mux.HandleFunc("/upload", manejarSubida)
 
 	// Configurar CORS
 	corsHandler := cors.New(cors.Options{
@@ -124,7 +127,7 @@ func manejarEjecucion(w http.ResponseWriter, r *http.Request) {
 			salida.WriteString(cmd + "\n")
 			continue
 		}
-		parts := strings.Fields(cmd)
+		parts := splitArgs(cmd)
 		if len(parts) == 0 {
 			continue
 		}
@@ -137,6 +140,76 @@ func manejarEjecucion(w http.ResponseWriter, r *http.Request) {
 	responder(w, salida.String(), http.StatusOK)
 }
 
+// manejarSubida crea un archivo con contenido arbitrario enviado en base64,
+// para guardar archivos binarios que no caben en un comando mkfile
+func manejarSubida(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodOptions {
+		http.Error(w, "Método no permitido", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if r.Method == http.MethodOptions {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	var entrada struct {
+		ID        string `json:"id"`
+		Path      string `json:"path"`
+		Contenido string `json:"contenido"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&entrada); err != nil {
+		responder(w, fmt.Sprintf("Error al leer el cuerpo: %v", err), http.StatusBadRequest)
+		return
+	}
+	if entrada.Path == "" {
+		responder(w, "Error: El campo path es obligatorio", http.StatusBadRequest)
+		return
+	}
+	content, err := base64.StdEncoding.DecodeString(entrada.Contenido)
+	if err != nil {
+		responder(w, fmt.Sprintf("Error: El contenido no es base64 válido: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	if currentSession == nil {
+		responder(w, "Error: No hay sesión activa", http.StatusUnauthorized)
+		return
+	}
+	id := entrada.ID
+	if id == "" {
+		id = currentSession.PartID
+	}
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		responder(w, fmt.Sprintf("Error: Partición %s no encontrada", id), http.StatusNotFound)
+		return
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		responder(w, fmt.Sprintf("Error al abrir disco: %v", err), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	if err := createFile(file, mp, entrada.Path, content); err != nil {
+		responder(w, fmt.Sprintf("Error: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := file.Sync(); err != nil {
+		responder(w, fmt.Sprintf("Error syncing disk: %v", err), http.StatusInternalServerError)
+		return
+	}
+	responder(w, fmt.Sprintf("Archivo %s subido exitosamente (%d bytes)", entrada.Path, len(content)), http.StatusOK)
+}
+
 // manejarParticiones devuelve las particiones montadas, agrupadas por disco
 func manejarParticiones(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost && r.Method != http.MethodOptions {
@@ -199,6 +272,12 @@ func responder(w http.ResponseWriter, mensaje string, status int) {
 // ejecutarComando analiza y ejecuta un comando
 func ejecutarComando(command string, params map[string]string) string {
 	var salida strings.Builder
+
+	// Los comandos de archivos usan la partición de la sesión cuando no se indica -id
+	if _, hasID := params["id"]; !hasID && currentSession != nil && sessionPartitionCommands[strings.ToUpper(command)] {
+		params["id"] = currentSession.PartID
+	}
+
 	switch strings.ToUpper(command) {
 	case "MKDISK":
 		return mkdisk(params)
@@ -257,11 +336,83 @@ func ejecutarComando(command string, params map[string]string) string {
 		return recovery(params)
 	case "LOSS":
 		return loss(params)
+	case "CHECKDISK":
+		return checkdisk(params)
+	case "DEFRAG":
+		return defrag(params)
+	case "RESIZEFS":
+		return resizefs(params)
+	case "FSCK":
+		return fsck(params)
+	case "RESTORESB":
+		return restoresb(params)
+	case "LN":
+		return ln(params)
+	case "EXPORT":
+		return export(params)
+	case "IMPORT":
+		return importFiles(params)
+	case "TAR":
+		return tarCmd(params)
+	case "UNTAR":
+		return untar(params)
+	case "GREP":
+		return grep(params)
+	case "STAT":
+		return stat(params)
+	case "DF":
+		return df(params)
+	case "DU":
+		return du(params)
+	case "CD":
+		return cd(params)
+	case "PWD":
+		return pwd(params)
+	case "TOUCH":
+		return touch(params)
+	case "TRASH":
+		return trash(params)
+	case "RESTORE":
+		return restore(params)
 	default:
 		return fmt.Sprintf("Comando %s no reconocido", command)
 	}
 }
 
+// sessionPartitionCommands son los comandos cuyo -id es opcional y por defecto es la partición de la sesión
+var sessionPartitionCommands = map[string]bool{
+	"CAT": true, "LS": true, "REMOVE": true, "COPY": true, "MOVE": true, "FIND": true,
+	"CHOWN": true, "CHMOD": true, "EDIT": true, "RENAME": true, "LN": true,
+	"EXPORT": true, "IMPORT": true, "TAR": true, "UNTAR": true, "GREP": true,
+	"STAT": true, "DU": true, "TOUCH": true, "TRASH": true, "RESTORE": true,
+}
+
+// splitArgs separa un comando por espacios respetando los valores entre comillas,
+// de modo que -text="hola mundo" llega completo a parseParameters
+func splitArgs(cmd string) []string {
+	var args []string
+	var current strings.Builder
+	inQuotes := false
+	for _, r := range cmd {
+		switch {
+		case r == '"':
+			inQuotes = !inQuotes
+			current.WriteRune(r)
+		case (r == ' ' || r == '\t') && !inQuotes:
+			if current.Len() > 0 {
+				args = append(args, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		args = append(args, current.String())
+	}
+	return args
+}
+
 // parseParameters convierte argumentos en un mapa de clave-valor
 func parseParameters(args []string) map[string]string {
 	params := make(map[string]string)
@@ -272,6 +423,9 @@ func parseParameters(args []string) map[string]string {
 				key := strings.ToLower(parts[0])
 				value := strings.Trim(parts[1], "\"")
 				params[key] = value
+			} else {
+				// Banderas sin valor (ej. -p, -fix) se registran vacías
+				params[strings.ToLower(parts[0])] = ""
 			}
 		}
 	}
@@ -302,6 +456,12 @@ func mkdisk(params map[string]string) string {
 		return salida.String()
 	}
 
+	// Verificar si es operación RESIZE
+	if _, hasResize := params["resize"]; hasResize {
+		_, extend := params["extend"]
+		return resizeDisk(path, size, extend)
+	}
+
 	fitByte := byte('F')
 	if fit != "" {
 		switch strings.ToUpper(fit) {
@@ -368,6 +528,73 @@ func mkdisk(params map[string]string) string {
 	return salida.String()
 }
 
+// resizeDisk amplía un disco existente y opcionalmente extiende su última partición
+func resizeDisk(path string, size int64, extend bool) string {
+	var salida strings.Builder
+	if size > math.MaxInt32 {
+		salida.WriteString(fmt.Sprintf("Error: El tamaño máximo de un disco es %d bytes", math.MaxInt32))
+		return salida.String()
+	}
+
+	file, err := os.OpenFile(path, os.O_RDWR, 0644)
+	if err != nil {
+		salida.WriteString(fmt.Sprintf("Error al abrir disco: %v", err))
+		return salida.String()
+	}
+	defer file.Close()
+
+	mbr, err := readMBR(file)
+	if err != nil {
+		salida.WriteString(fmt.Sprintf("Error al leer MBR: %v", err))
+		return salida.String()
+	}
+
+	if int32(size) <= mbr.MbrTamano {
+		salida.WriteString(fmt.Sprintf("Error: El nuevo tamaño (%d) debe ser mayor al actual (%d)", size, mbr.MbrTamano))
+		return salida.String()
+	}
+
+	// Extender la partición que termina más cerca del final del disco
+	lastIndex := -1
+	for i, part := range mbr.MbrPartitions {
+		if part.PartStatus != '1' {
+			continue
+		}
+		if lastIndex == -1 || part.PartStart+part.PartSize > mbr.MbrPartitions[lastIndex].PartStart+mbr.MbrPartitions[lastIndex].PartSize {
+			lastIndex = i
+		}
+	}
+	if extend && lastIndex == -1 {
+		salida.WriteString("Error: El disco no tiene particiones para extender")
+		return salida.String()
+	}
+
+	oldSize := mbr.MbrTamano
+	mbr.MbrTamano = int32(size)
+	var added int32
+	if extend {
+		last := &mbr.MbrPartitions[lastIndex]
+		added = mbr.MbrTamano - (last.PartStart + last.PartSize)
+		last.PartSize += added
+	}
+
+	// Los bytes nuevos del archivo quedan en cero
+	if err := file.Truncate(size); err != nil {
+		salida.WriteString(fmt.Sprintf("Error al ampliar disco: %v", err))
+		return salida.String()
+	}
+	if err := writeMBR(file, mbr); err != nil {
+		salida.WriteString(fmt.Sprintf("Error al escribir MBR: %v", err))
+		return salida.String()
+	}
+	salida.WriteString(fmt.Sprintf("Disco %s ampliado de %d a %d bytes", path, oldSize, size))
+	if extend {
+		salida.WriteString(fmt.Sprintf("\nPartición %s extendida %d bytes", strings.Trim(string(mbr.MbrPartitions[lastIndex].PartName[:]), "\x00"), added))
+	}
+
+	return salida.String()
+}
+
 // rmdisk elimina un disco virtual
 func rmdisk(params map[string]string) string {
 	var salida strings.Builder
@@ -1207,6 +1434,9 @@ func mount(params map[string]string) string {
 			DiskOrder: diskOrder,
 		})
 
+		if err := stampMount(file, &mountedPartitions[len(mountedPartitions)-1], true); err != nil {
+			fmt.Printf("No se pudo registrar el montaje en el superbloque: %v\n", err)
+		}
 		fmt.Printf("Montada partición: ID=%s, Path=%s, Name=%s, Correl=%d, DiskOrder=%c\n", id, path, name, correl, diskOrder)
 		salida.WriteString(fmt.Sprintf("Partición %s montada exitosamente con ID %s", name, id))
 		return salida.String()
@@ -1285,6 +1515,9 @@ func mount(params map[string]string) string {
 						DiskOrder: diskOrder,
 					})
 
+					if err := stampMount(file, &mountedPartitions[len(mountedPartitions)-1], true); err != nil {
+						fmt.Printf("No se pudo registrar el montaje en el superbloque: %v\n", err)
+					}
 					fmt.Printf("Montada partición lógica: ID=%s, Path=%s, Name=%s, Correl=%d, DiskOrder=%c\n", id, path, name, correl, diskOrder)
 					salida.WriteString(fmt.Sprintf("Partición %s montada exitosamente con ID %s", name, id))
 					return salida.String()
@@ -1367,6 +1600,26 @@ func writeMBR(file *os.File, mbr *MBR) error {
 	return binary.Write(file, binary.LittleEndian, mbr)
 }
 
+// readEBR lee un EBR en la posición indicada
+func readEBR(file *os.File, pos int64) (EBR, error) {
+	var ebr EBR
+	if _, err := file.Seek(pos, 0); err != nil {
+		return EBR{}, err
+	}
+	if err := binary.Read(file, binary.LittleEndian, &ebr); err != nil {
+		return EBR{}, err
+	}
+	return ebr, nil
+}
+
+// writeEBR escribe un EBR en la posición indicada
+func writeEBR(file *os.File, pos int64, ebr *EBR) error {
+	if _, err := file.Seek(pos, 0); err != nil {
+		return err
+	}
+	return binary.Write(file, binary.LittleEndian, ebr)
+}
+
 // findSpace encuentra espacio para una partición
 func findSpace(mbr *MBR, size int32, fit byte) (int32, error) {
 	start := int32(binary.Size(MBR{}))
@@ -1463,8 +1716,11 @@ func findSpace(mbr *MBR, size int32, fit byte) (int32, error) {
 func ls(params map[string]string) string {
 	path, hasPath := params["path"]
 	id, hasID := params["id"]
-	if !hasPath || !hasID {
-		return "Error: Parámetros -path y -id son obligatorios"
+	if !hasID {
+		return "Error: Parámetro -id es obligatorio"
+	}
+	if !hasPath {
+		path = "." // Carpeta actual de la sesión
 	}
 
 	if currentSession == nil {
@@ -1495,12 +1751,9 @@ func ls(params map[string]string) string {
 	}
 
 	// Procesar la ruta
-	var pathParts []string
-	if path != "/" {
-		pathParts, err = normalizePath(path)
-		if err != nil {
-			return fmt.Sprintf("Error: %v", err)
-		}
+	pathParts, err := splitPath(path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 
 	// Navegar hasta la carpeta
@@ -1520,6 +1773,9 @@ func ls(params map[string]string) string {
 	if inode.IType != '0' {
 		return fmt.Sprintf("Error: %s no es una carpeta", path)
 	}
+	if !hasReadPermission(inode, currentSession) {
+		return fmt.Sprintf("Error: Permiso denegado para listar %s", path)
+	}
 
 	// Listar contenido
 	seen := make(map[string]bool)
@@ -1543,19 +1799,32 @@ func ls(params map[string]string) string {
 				continue
 			}
 			// Verificar que el inodo sea válido
-			if itemInode.IType != '0' && itemInode.IType != '1' {
+			if itemInode.IType != '0' && itemInode.IType != '1' && itemInode.IType != '2' {
 				continue
 			}
-			contents = append(contents, map[string]interface{}{
+			item := map[string]interface{}{
 				"name":          name,
 				"type":          string(itemInode.IType),
 				"size":          itemInode.ISize,
 				"creation_date": strings.Trim(string(itemInode.ICtime[:]), "\x00"),
+				"modified_date": strings.Trim(string(itemInode.IMtime[:]), "\x00"),
 				"permissions":   fmt.Sprintf("%03d", itemInode.IPerm),
-			})
+				"links":         linkCount(itemInode),
+			}
+			if itemInode.IType == '2' {
+				if target, err := readSymlinkTarget(file, sb, itemInode); err == nil {
+					item["target"] = target
+				}
+			}
+			contents = append(contents, item)
 		}
 	}
 
+	// Leer una carpeta cuenta como acceso
+	if err = touchInode(file, sb, currentInode, true, false); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
 	// Devolver resultado como JSON
 	result, err := json.Marshal(contents)
 	if err != nil {
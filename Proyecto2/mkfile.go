@@ -3,20 +3,29 @@ package main
 import (
 	"encoding/binary"
 	"fmt"
-	"math"
 	"os"
 	"strconv"
 	"strings"
-	"time"
 )
 
-func normalizePath(path string) ([]string, error) {
+// splitPath convierte una ruta en sus componentes desde la raíz. Las rutas relativas se
+// toman desde la carpeta actual de la sesión y . y .. se resuelven sin consultar el disco,
+// como cd en una shell. La raíz devuelve una lista vacía.
+func splitPath(path string) ([]string, error) {
 	path = strings.Trim(path, "\"")
-	parts := strings.Split(path, "/")
+	if !strings.HasPrefix(path, "/") && currentSession != nil {
+		path = currentSession.Cwd + "/" + path
+	}
 	var result []string
-	for _, part := range parts {
+	for _, part := range strings.Split(path, "/") {
 		part = strings.TrimSpace(part)
-		if part == "" {
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if len(result) > 0 {
+				result = result[:len(result)-1]
+			}
 			continue
 		}
 		if len(part) > 12 {
@@ -24,6 +33,15 @@ func normalizePath(path string) ([]string, error) {
 		}
 		result = append(result, part)
 	}
+	return result, nil
+}
+
+// normalizePath es splitPath para rutas que deben nombrar algo distinto de la raíz
+func normalizePath(path string) ([]string, error) {
+	result, err := splitPath(path)
+	if err != nil {
+		return nil, err
+	}
 	if len(result) == 0 {
 		return nil, fmt.Errorf("ruta inválida")
 	}
@@ -46,9 +64,10 @@ func mkfile(params map[string]string) string {
 		return "Error: No hay sesión activa"
 	}
 
-	// Obtener parámetros opcionales
+	// Obtener parámetros opcionales: -cont es un archivo del anfitrión, -text el contenido literal
 	sizeStr, hasSize := params["size"]
 	cont, hasCont := params["cont"]
+	text, hasText := params["text"]
 	size := int32(0)
 	if hasSize {
 		s, err := strconv.Atoi(sizeStr)
@@ -57,11 +76,17 @@ func mkfile(params map[string]string) string {
 		}
 		size = int32(s)
 	}
-	if !hasCont && !hasSize {
-		return "Error: Se requiere -cont o -size"
+	sources := 0
+	for _, has := range []bool{hasCont, hasText, hasSize} {
+		if has {
+			sources++
+		}
+	}
+	if sources == 0 {
+		return "Error: Se requiere -cont, -text o -size"
 	}
-	if hasCont && hasSize {
-		return "Error: No se pueden especificar -cont y -size juntos"
+	if sources > 1 {
+		return "Error: Solo se puede especificar uno de -cont, -text y -size"
 	}
 
 	// Obtener partición montada
@@ -87,53 +112,58 @@ func mkfile(params map[string]string) string {
 		return fmt.Sprintf("Error al leer superbloque: %v", err)
 	}
 
-	// Calcular partStart como en readSuperblock
-	mbr, err := readMBR(file)
-	if err != nil {
-		return fmt.Sprintf("Error al leer MBR: %v", err)
-	}
-	var partStart int32
-	foundPart := false
-	for _, p := range mbr.MbrPartitions {
-		name := strings.Trim(string(p.PartName[:]), "\x00")
-		if name == mp.Name {
-			partStart = p.PartStart
-			foundPart = true
-			break
+	// Preparar contenido, validando el tamaño antes de leer el archivo del anfitrión
+	var content []byte
+	switch {
+	case hasCont:
+		hostPath, err := resolveHostPath(cont)
+		if err != nil {
+			return fmt.Sprintf("Error: %v", err)
 		}
-	}
-	if !foundPart {
-		for _, p := range mbr.MbrPartitions {
-			if p.PartType == 'E' {
-				currentPos := p.PartStart
-				for currentPos != -1 {
-					file.Seek(int64(currentPos), 0)
-					var ebr EBR
-					if err := binary.Read(file, binary.LittleEndian, &ebr); err != nil {
-						continue
-					}
-					name := strings.Trim(string(ebr.PartName[:]), "\x00")
-					if name == mp.Name && ebr.PartSize > 0 {
-						partStart = ebr.PartStart
-						foundPart = true
-						break
-					}
-					currentPos = ebr.PartNext
-				}
-			}
-			if foundPart {
-				break
-			}
+		info, err := os.Stat(hostPath)
+		if err != nil {
+			return fmt.Sprintf("Error: No se puede leer %s: %v", cont, err)
+		}
+		if info.IsDir() {
+			return fmt.Sprintf("Error: %s es una carpeta", cont)
+		}
+		if err := checkFileFits(sb, info.Size()); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if content, err = os.ReadFile(hostPath); err != nil {
+			return fmt.Sprintf("Error: No se puede leer %s: %v", cont, err)
+		}
+	case hasText:
+		content = []byte(text)
+	default:
+		content = make([]byte, size)
+		for i := range content {
+			content[i] = '0' // Rellenar con '0'
 		}
 	}
-	if !foundPart {
-		return fmt.Sprintf("Error: Partición %s no encontrada en MBR ni EBR", mp.Name)
+
+	// Crear el archivo
+	if err := createFile(file, mp, path, content); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+
+	return fmt.Sprintf("Archivo %s creado exitosamente", path)
+}
+
+// createFile crea el archivo path en la partición con el contenido exacto de content
+func createFile(file *os.File, mp *MountedPartition, path string, content []byte) error {
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Errorf("error al leer superbloque: %v", err)
 	}
 
 	// Procesar la ruta
 	pathParts, err := normalizePath(path)
 	if err != nil {
-		return fmt.Sprintf("Error: %v", err)
+		return err
 	}
 	fileName := pathParts[len(pathParts)-1]
 	parentPath := pathParts[:len(pathParts)-1]
@@ -141,242 +171,64 @@ func mkfile(params map[string]string) string {
 	// Navegar hasta la carpeta padre
 	currentInode, err := navigateToParent(file, sb, parentPath)
 	if err != nil {
-		return fmt.Sprintf("Error al navegar a la carpeta padre: %v", err)
+		return fmt.Errorf("error al navegar a la carpeta padre: %v", err)
 	}
 
 	// Verificar permisos de escritura en la carpeta padre
 	parentInode, err := readInode(file, sb, currentInode)
 	if err != nil {
-		return fmt.Sprintf("Error al leer inodo padre %d: %v", currentInode, err)
+		return fmt.Errorf("error al leer inodo padre %d: %v", currentInode, err)
 	}
 	if !hasWritePermission(parentInode, currentSession.UserID, currentSession.GroupID) {
-		return "Error: Permisos insuficientes para escribir en la carpeta padre"
+		return fmt.Errorf("permisos insuficientes para escribir en la carpeta padre")
 	}
 
 	// Verificar si el archivo ya existe
-	for _, blockIndex := range parentInode.IBlock {
-		if blockIndex == -1 {
-			continue
-		}
-		folderBlock, err := readFolderBlock(file, sb, blockIndex)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bloque %d: %v", blockIndex, err)
-		}
-		for _, content := range folderBlock.BContent {
-			name := strings.Trim(string(content.BName[:]), "\x00")
-			if name == fileName {
-				return fmt.Sprintf("Error: El archivo %s ya existe", fileName)
-			}
-		}
+	if existing, err := lookupEntry(file, sb, currentInode, fileName); err != nil {
+		return err
+	} else if existing != -1 {
+		return fmt.Errorf("el archivo %s ya existe", fileName)
 	}
 
-	// Leer bitmaps
-	bitmapInodes := make([]byte, sb.SInodesCount)
-	bitmapBlocks := make([]byte, sb.SBlocksCount)
-	file.Seek(int64(sb.SBmInodeStart), 0)
-	_, err = file.Read(bitmapInodes)
-	if err != nil {
-		return fmt.Sprintf("Error al leer bitmap de inodos: %v", err)
-	}
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	_, err = file.Read(bitmapBlocks)
-	if err != nil {
-		return fmt.Sprintf("Error al leer bitmap de bloques: %v", err)
-	}
-
-	// Encontrar inodo libre
-	newInodeIndex := int32(-1)
-	for i := int32(0); i < sb.SInodesCount; i++ {
-		if bitmapInodes[i] == 0 {
-			newInodeIndex = i
-			bitmapInodes[i] = 1
-			break
-		}
-	}
-	if newInodeIndex == -1 {
-		return "Error: No hay inodos libres"
+	// Validar que el contenido quepa antes de asignar
+	if err := checkFileFits(sb, int64(len(content))); err != nil {
+		return err
 	}
 
-	// Preparar contenido
-	var content []byte
-	if hasCont {
-		content = []byte(cont)
-	} else {
-		content = make([]byte, size)
-		for i := range content {
-			content[i] = '0' // Rellenar con '0'
-		}
-	}
-
-	// Calcular bloques necesarios
-	numBlocks := int32(math.Ceil(float64(len(content)) / 64.0))
-	if numBlocks > 15 {
-		bitmapInodes[newInodeIndex] = 0
-		file.Seek(int64(sb.SBmInodeStart), 0)
-		file.Write(bitmapInodes)
-		return fmt.Sprintf("Error: El archivo requiere %d bloques, máximo 15", numBlocks)
-	}
-
-	// Asignar bloques
-	newBlocks := make([]int32, numBlocks)
-	currentBlock := int32(2) // Comenzar después de bloques iniciales
-	for i := int32(0); i < numBlocks; i++ {
-		for currentBlock < sb.SBlocksCount && bitmapBlocks[currentBlock] == 1 {
-			currentBlock++
-		}
-		if currentBlock >= sb.SBlocksCount {
-			bitmapInodes[newInodeIndex] = 0
-			file.Seek(int64(sb.SBmInodeStart), 0)
-			file.Write(bitmapInodes)
-			return "Error: No hay bloques libres"
-		}
-		newBlocks[i] = currentBlock
-		bitmapBlocks[currentBlock] = 1
-		currentBlock++
-	}
-
-	// Crear inodo para el archivo
-	fecha := time.Now().Format("2006-01-02 15:04:05")
-	newInode := Inode{
-		IUid:  currentSession.UserID,
-		IGid:  currentSession.GroupID,
-		ISize: int32(len(content)),
-		IType: '1',
-		IPerm: 664,
-	}
-	for i, blockIndex := range newBlocks {
-		newInode.IBlock[i] = blockIndex
-	}
-	copy(newInode.IAtime[:], fecha)
-	copy(newInode.ICtime[:], fecha)
-	copy(newInode.IMtime[:], fecha)
-
-	// Escribir bloques de archivo
-	for i, blockIndex := range newBlocks {
-		var block FileBlock
-		for j := range block.BContent {
-			block.BContent[j] = 0
-		}
-		start := int32(i * 64)
-		end := start + 64
-		if end > int32(len(content)) {
-			end = int32(len(content))
-		}
-		copy(block.BContent[:], content[start:end])
-		file.Seek(int64(sb.SBlockStart+blockIndex*sb.SBlockSize), 0)
-		if err = binary.Write(file, binary.LittleEndian, &block); err != nil {
-			return fmt.Sprintf("Error al escribir bloque %d: %v", blockIndex, err)
-		}
-	}
-
-	// Escribir inodo
-	file.Seek(int64(sb.SInodeStart+newInodeIndex*sb.SInodeSize), 0)
-	if err = binary.Write(file, binary.LittleEndian, &newInode); err != nil {
-		return fmt.Sprintf("Error al escribir inodo %d: %v", newInodeIndex, err)
+	// Asignar inodo y bloques y escribir el contenido
+	alloc, err := newAllocator(file, mp, &sb)
+	if err != nil {
+		return err
 	}
-	if err = file.Sync(); err != nil {
-		return fmt.Sprintf("Error syncing disk: %v", err)
+	newInodeIndex, err := writeNewInode(file, sb, alloc, '1', 664, content)
+	if err != nil {
+		alloc.Rollback()
+		return err
 	}
 
 	// Actualizar carpeta padre
-	found := false
-	for _, blockIndex := range parentInode.IBlock {
-		if blockIndex == -1 {
-			continue
-		}
-		folderBlock, err := readFolderBlock(file, sb, blockIndex)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bloque %d: %v", blockIndex, err)
-		}
-		for i := range folderBlock.BContent {
-			name := strings.Trim(string(folderBlock.BContent[i].BName[:]), "\x00")
-			if name == "" {
-				copy(folderBlock.BContent[i].BName[:], fileName)
-				folderBlock.BContent[i].BInode = newInodeIndex
-				file.Seek(int64(sb.SBlockStart+blockIndex*sb.SBlockSize), 0)
-				if err = binary.Write(file, binary.LittleEndian, &folderBlock); err != nil {
-					return fmt.Sprintf("Error al actualizar bloque %d: %v", blockIndex, err)
-				}
-				found = true
-				break
-			}
-		}
-		if found {
-			break
-		}
-	}
-
-	if !found {
-		// Asignar nuevo bloque a la carpeta padre si no hay espacio
-		for currentBlock < sb.SBlocksCount && bitmapBlocks[currentBlock] == 1 {
-			currentBlock++
-		}
-		if currentBlock >= sb.SBlocksCount {
-			// Liberar inodo y bloques asignados
-			bitmapInodes[newInodeIndex] = 0
-			for _, blockIndex := range newBlocks {
-				bitmapBlocks[blockIndex] = 0
-			}
-			file.Seek(int64(sb.SBmInodeStart), 0)
-			file.Write(bitmapInodes)
-			file.Seek(int64(sb.SBmBlockStart), 0)
-			file.Write(bitmapBlocks)
-			return "Error: No hay bloques libres para la carpeta padre"
-		}
-		newFolderBlockIndex := currentBlock
-		bitmapBlocks[currentBlock] = 1
-
-		var newFolderBlock FolderBlock
-		copy(newFolderBlock.BContent[0].BName[:], fileName)
-		newFolderBlock.BContent[0].BInode = newInodeIndex
-		file.Seek(int64(sb.SBlockStart+newFolderBlockIndex*sb.SBlockSize), 0)
-		if err = binary.Write(file, binary.LittleEndian, &newFolderBlock); err != nil {
-			return fmt.Sprintf("Error al escribir bloque %d: %v", newFolderBlockIndex, err)
-		}
-
-		// Actualizar inodo padre
-		for i := range parentInode.IBlock {
-			if parentInode.IBlock[i] == -1 {
-				parentInode.IBlock[i] = newFolderBlockIndex
-				break
-			}
-		}
-		file.Seek(int64(sb.SInodeStart+currentInode*sb.SInodeSize), 0)
-		if err = binary.Write(file, binary.LittleEndian, &parentInode); err != nil {
-			return fmt.Sprintf("Error al actualizar inodo padre %d: %v", currentInode, err)
-		}
+	if err = addFolderEntry(file, sb, alloc, currentInode, fileName, newInodeIndex); err != nil {
+		alloc.Rollback()
+		return err
 	}
 
-	// Escribir bitmaps
-	file.Seek(int64(sb.SBmInodeStart), 0)
-	if _, err = file.Write(bitmapInodes); err != nil {
-		return fmt.Sprintf("Error al escribir bitmap de inodos: %v", err)
-	}
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	if _, err = file.Write(bitmapBlocks); err != nil {
-		return fmt.Sprintf("Error al escribir bitmap de bloques: %v", err)
-	}
+	// Escribir bitmaps y superbloque
+	return alloc.Commit()
+}
 
-	// Actualizar superbloque
-	file.Seek(int64(partStart), 0)
-	var sbUpdated Superblock
-	if err = binary.Read(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Sprintf("Error al leer superbloque: %v", err)
-	}
-	sbUpdated.SFreeInodesCount--
-	sbUpdated.SFreeBlocksCount -= numBlocks
-	if !found {
-		sbUpdated.SFreeBlocksCount-- // Bloque adicional para la carpeta padre
+// checkFileFits valida que un archivo de size bytes quepa en un inodo y en los bloques libres
+func checkFileFits(sb Superblock, size int64) error {
+	numBlocks := (size + int64(sb.SBlockSize) - 1) / int64(sb.SBlockSize)
+	if numBlocks > 15 {
+		return fmt.Errorf("el archivo requiere %d bloques, máximo 15 (%d bytes)", numBlocks, 15*sb.SBlockSize)
 	}
-	file.Seek(int64(partStart), 0)
-	if err = binary.Write(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Sprintf("Error al escribir superbloque: %v", err)
+	if sb.SFreeInodesCount < 1 {
+		return fmt.Errorf("no hay inodos libres")
 	}
-	if err = file.Sync(); err != nil {
-		return fmt.Sprintf("Error syncing disk: %v", err)
+	if numBlocks > int64(sb.SFreeBlocksCount) {
+		return fmt.Errorf("espacio insuficiente: el archivo requiere %d bloques y hay %d libres", numBlocks, sb.SFreeBlocksCount)
 	}
-
-	return fmt.Sprintf("Archivo %s creado exitosamente", path)
+	return nil
 }
 
 // MKDIR: Crea una carpeta en la ruta especificada, con soporte para creación recursiva (-p).
@@ -486,6 +338,10 @@ func mkdir(params map[string]string) string {
 				if strings.HasPrefix(result, "Error") {
 					return result
 				}
+				// Releer el superbloque para conservar los contadores actualizados
+				if sb, err = readSuperblock(file, mp); err != nil {
+					return fmt.Sprintf("Error al leer superbloque: %v", err)
+				}
 				// Actualizar currentInode
 				nextInode, err = navigateToParent(file, sb, currentPathParts)
 				if err != nil {
@@ -512,50 +368,6 @@ func mkdir(params map[string]string) string {
 // createFolder: Función auxiliar para crear una carpeta en el sistema de archivos.
 func createFolder(file *os.File, sb Superblock, mp *MountedPartition, parentInodeIndex int32, folderName string) string {
 	var err error
-	fmt.Printf("Creating folder %s, parentInode=%d\n", folderName, parentInodeIndex)
-
-	// Calcular partStart para el superbloque
-	mbr, err := readMBR(file)
-	if err != nil {
-		return fmt.Sprintf("Error al leer MBR: %v", err)
-	}
-	var partStart int32
-	foundPart := false
-	for _, p := range mbr.MbrPartitions {
-		name := strings.Trim(string(p.PartName[:]), "\x00")
-		if name == mp.Name {
-			partStart = p.PartStart
-			foundPart = true
-			break
-		}
-	}
-	if !foundPart {
-		for _, p := range mbr.MbrPartitions {
-			if p.PartType == 'E' {
-				currentPos := p.PartStart
-				for currentPos != -1 {
-					file.Seek(int64(currentPos), 0)
-					var ebr EBR
-					if err = binary.Read(file, binary.LittleEndian, &ebr); err != nil {
-						continue
-					}
-					name := strings.Trim(string(ebr.PartName[:]), "\x00")
-					if name == mp.Name && ebr.PartSize > 0 {
-						partStart = ebr.PartStart
-						foundPart = true
-						break
-					}
-					currentPos = ebr.PartNext
-				}
-			}
-			if foundPart {
-				break
-			}
-		}
-	}
-	if !foundPart {
-		return fmt.Sprintf("Error: Partición %s no encontrada en MBR ni EBR", mp.Name)
-	}
 
 	// Verificar permisos de escritura en la carpeta padre
 	parentInode, err := readInode(file, sb, parentInodeIndex)
@@ -583,238 +395,37 @@ func createFolder(file *os.File, sb Superblock, mp *MountedPartition, parentInod
 		}
 	}
 
-	// Leer bitmaps
-	bitmapInodes := make([]byte, sb.SInodesCount)
-	bitmapBlocks := make([]byte, sb.SBlocksCount)
-	file.Seek(int64(sb.SBmInodeStart), 0)
-	_, err = file.Read(bitmapInodes)
+	// Asignar y escribir la carpeta con sus entradas . y ..
+	alloc, err := newAllocator(file, mp, &sb)
 	if err != nil {
-		return fmt.Sprintf("Error al leer bitmap de inodos: %v", err)
+		return fmt.Sprintf("Error: %v", err)
 	}
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	_, err = file.Read(bitmapBlocks)
+	newInodeIndex, err := writeNewFolder(file, sb, alloc, parentInodeIndex, 664)
 	if err != nil {
-		return fmt.Sprintf("Error al leer bitmap de bloques: %v", err)
-	}
-
-	// Encontrar inodo libre
-	newInodeIndex := int32(-1)
-	for i := int32(0); i < sb.SInodesCount; i++ {
-		if bitmapInodes[i] == 0 {
-			newInodeIndex = i
-			bitmapInodes[i] = 1
-			break
-		}
-	}
-	if newInodeIndex == -1 {
-		return "Error: No hay inodos libres"
-	}
-	fmt.Printf("Allocated inode=%d\n", newInodeIndex)
-
-	// Encontrar bloque libre
-	currentBlock := int32(2)
-	for currentBlock < sb.SBlocksCount && bitmapBlocks[currentBlock] == 1 {
-		currentBlock++
-	}
-	if currentBlock >= sb.SBlocksCount {
-		bitmapInodes[newInodeIndex] = 0
-		file.Seek(int64(sb.SBmInodeStart), 0)
-		file.Write(bitmapInodes)
-		return "Error: No hay bloques libres"
-	}
-	newBlockIndex := currentBlock
-	bitmapBlocks[currentBlock] = 1
-	fmt.Printf("Allocated block=%d\n", newBlockIndex)
-
-	// Crear inodo para la carpeta
-	fecha := time.Now().Format("2006-01-02 15:04:05")
-	newInode := Inode{
-		IUid:  currentSession.UserID,
-		IGid:  currentSession.GroupID,
-		ISize: 0,
-		IType: '0',
-		IPerm: 664,
-	}
-	newInode.IBlock[0] = newBlockIndex
-	copy(newInode.IAtime[:], fecha)
-	copy(newInode.ICtime[:], fecha)
-	copy(newInode.IMtime[:], fecha)
-
-	// Crear bloque de carpeta con entradas . y ..
-	var folderBlock FolderBlock
-	// Inicializar todas las entradas como vacías
-	for i := range folderBlock.BContent {
-		folderBlock.BContent[i].BInode = -1
-	}
-	// Entrada para .
-	copy(folderBlock.BContent[0].BName[:], ".")
-	folderBlock.BContent[0].BInode = newInodeIndex
-	// Entrada para ..
-	copy(folderBlock.BContent[1].BName[:], "..")
-	folderBlock.BContent[1].BInode = parentInodeIndex
-
-	// Escribir inodo
-	file.Seek(int64(sb.SInodeStart+newInodeIndex*sb.SInodeSize), 0)
-	if err = binary.Write(file, binary.LittleEndian, &newInode); err != nil {
-		return fmt.Sprintf("Error al escribir inodo %d: %v", newInodeIndex, err)
-	}
-	fmt.Printf("Wrote inode=%d\n", newInodeIndex)
-
-	// Escribir bloque
-	file.Seek(int64(sb.SBlockStart+newBlockIndex*sb.SBlockSize), 0)
-	if err = binary.Write(file, binary.LittleEndian, &folderBlock); err != nil {
-		return fmt.Sprintf("Error al escribir bloque %d: %v", newBlockIndex, err)
-	}
-	fmt.Printf("Wrote block=%d\n", newBlockIndex)
-
-	if err = file.Sync(); err != nil {
-		return fmt.Sprintf("Error syncing disk: %v", err)
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
 	}
 
 	// Actualizar carpeta padre
-	found := false
-	for i, blockIndex := range parentInode.IBlock {
-		if blockIndex == -1 {
-			// Crear un nuevo bloque si no hay espacio
-			currentBlock++
-			for currentBlock < sb.SBlocksCount && bitmapBlocks[currentBlock] == 1 {
-				currentBlock++
-			}
-			if currentBlock >= sb.SBlocksCount {
-				// Liberar inodo y bloque asignados
-				bitmapInodes[newInodeIndex] = 0
-				bitmapBlocks[newBlockIndex] = 0
-				file.Seek(int64(sb.SBmInodeStart), 0)
-				file.Write(bitmapInodes)
-				file.Seek(int64(sb.SBmBlockStart), 0)
-				file.Write(bitmapBlocks)
-				return "Error: No hay bloques libres para la carpeta padre"
-			}
-			newFolderBlockIndex := currentBlock
-			bitmapBlocks[currentBlock] = 1
-			fmt.Printf("Allocated new folder block=%d for parent\n", newFolderBlockIndex)
-
-			var newFolderBlock FolderBlock
-			// Inicializar todas las entradas como vacías
-			for j := range newFolderBlock.BContent {
-				newFolderBlock.BContent[j].BInode = -1
-			}
-			// Añadir la entrada para el nuevo directorio
-			copy(newFolderBlock.BContent[0].BName[:], folderName)
-			newFolderBlock.BContent[0].BInode = newInodeIndex
-			file.Seek(int64(sb.SBlockStart+newFolderBlockIndex*sb.SBlockSize), 0)
-			if err = binary.Write(file, binary.LittleEndian, &newFolderBlock); err != nil {
-				return fmt.Sprintf("Error al escribir bloque %d: %v", newFolderBlockIndex, err)
-			}
-
-			// Actualizar inodo padre
-			parentInode.IBlock[i] = newFolderBlockIndex
-			file.Seek(int64(sb.SInodeStart+parentInodeIndex*sb.SInodeSize), 0)
-			if err = binary.Write(file, binary.LittleEndian, &parentInode); err != nil {
-				return fmt.Sprintf("Error al actualizar inodo padre %d: %v", parentInodeIndex, err)
-			}
-			fmt.Printf("Updated parent inode=%d with new block=%d\n", parentInodeIndex, newFolderBlockIndex)
-			found = true
-			break
-		}
-
-		folderBlock, err = readFolderBlock(file, sb, blockIndex)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bloque %d: %v", blockIndex, err)
-		}
-		for j := range folderBlock.BContent {
-			name := strings.Trim(string(folderBlock.BContent[j].BName[:]), "\x00")
-			if name == "" || folderBlock.BContent[j].BInode == -1 {
-				copy(folderBlock.BContent[j].BName[:], folderName)
-				folderBlock.BContent[j].BInode = newInodeIndex
-				file.Seek(int64(sb.SBlockStart+blockIndex*sb.SBlockSize), 0)
-				if err = binary.Write(file, binary.LittleEndian, &folderBlock); err != nil {
-					return fmt.Sprintf("Error al actualizar bloque %d: %v", blockIndex, err)
-				}
-				fmt.Printf("Updated parent block=%d with folder %s, inode=%d\n", blockIndex, folderName, newInodeIndex)
-				found = true
-				break
-			}
-		}
-		if found {
-			break
-		}
-	}
-
-	// Escribir bitmaps
-	file.Seek(int64(sb.SBmInodeStart), 0)
-	if _, err = file.Write(bitmapInodes); err != nil {
-		return fmt.Sprintf("Error al escribir bitmap de inodos: %v", err)
-	}
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	if _, err = file.Write(bitmapBlocks); err != nil {
-		return fmt.Sprintf("Error al escribir bitmap de bloques: %v", err)
+	if err = addFolderEntry(file, sb, alloc, parentInodeIndex, folderName, newInodeIndex); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
 	}
 
-	// Actualizar superbloque
-	file.Seek(int64(partStart), 0)
-	var sbUpdated Superblock
-	if err = binary.Read(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Sprintf("Error al leer superbloque: %v", err)
-	}
-	sbUpdated.SFreeInodesCount--
-	sbUpdated.SFreeBlocksCount--
-	if !found {
-		sbUpdated.SFreeBlocksCount-- // Bloque adicional para la carpeta padre
-	}
-	file.Seek(int64(partStart), 0)
-	if err = binary.Write(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Sprintf("Error al escribir superbloque: %v", err)
+	// Escribir bitmaps y superbloque
+	if err = alloc.Commit(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 	if err = file.Sync(); err != nil {
 		return fmt.Sprintf("Error syncing disk: %v", err)
 	}
 
-	fmt.Printf("Folder %s created successfully\n", folderName)
 	return fmt.Sprintf("Carpeta %s creada exitosamente", folderName)
 }
 
-// navigateToParent: Navega hasta la carpeta padre de una ruta.
+// navigateToParent: Navega hasta la carpeta padre de una ruta, siguiendo enlaces simbólicos.
 func navigateToParent(file *os.File, sb Superblock, pathParts []string) (int32, error) {
-	currentInode := int32(0) // Inodo raíz
-	for _, part := range pathParts {
-		if len(part) > 12 {
-			return 0, fmt.Errorf("el nombre %s excede 12 caracteres", part)
-		}
-		inode, err := readInode(file, sb, currentInode)
-		if err != nil {
-			return 0, fmt.Errorf("error al leer inodo %d: %v", currentInode, err)
-		}
-		if inode.IType != '0' {
-			return 0, fmt.Errorf("%s no es una carpeta", part)
-		}
-
-		found := false
-		for _, blockIndex := range inode.IBlock {
-			if blockIndex == -1 {
-				continue
-			}
-			folderBlock, err := readFolderBlock(file, sb, blockIndex)
-			if err != nil {
-				return 0, fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
-			}
-			for _, content := range folderBlock.BContent {
-				name := strings.Trim(string(content.BName[:]), "\x00")
-				if name == part {
-					currentInode = content.BInode
-					found = true
-					break
-				}
-			}
-			if found {
-				break
-			}
-		}
-		if !found {
-			return 0, fmt.Errorf("la carpeta %s no existe", part)
-		}
-	}
-	return currentInode, nil
+	return resolvePath(file, sb, pathParts, true)
 }
 
 // hasWritePermission: Verifica permisos de escritura en un inodo.
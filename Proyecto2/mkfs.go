@@ -1,7 +1,10 @@
 package main
 
 import (
+	"bytes"
+	"encoding/base64"
 	"encoding/binary"
+	"encoding/hex"
 	"fmt"
 	"math"
 	"os"
@@ -31,8 +34,27 @@ type Superblock struct {
 	SBlockStart      int32
 	SJournalStart    int32 // Inicio del journal
 	SJournalSize     int32 // Tamaño del journal
+	SReservedBlocks  int32 // Bloques reservados después de la raíz
+	SVersion         int32 // Formato de los bitmaps (fsVersionByteBitmap o fsVersionBitBitmap)
+	SAllocFit        int32 // Estrategia de asignación (allocFirstFit o allocNextFit)
 }
 
+// folderEntrySize es el tamaño en bytes de una entrada de carpeta
+const folderEntrySize = 16
+
+// fsGeometry agrupa los parámetros configurables con los que mkfs distribuye la partición
+type fsGeometry struct {
+	BlockSize  int32 // Tamaño de bloque en bytes
+	InodeRatio int32 // Bloques por inodo
+	Inodes     int32 // Cantidad fija de inodos (0 = calcular con InodeRatio)
+	Reserved   int32 // Bloques reservados después de la raíz
+	Version    int32 // Formato de los bitmaps
+	InodeSize  int32 // Tamaño de inodo en bytes (0 = el del formato actual)
+}
+
+// defaultGeometry usa bloques de 64 bytes, 3 bloques por inodo y bitmaps de un bit por objeto
+var defaultGeometry = fsGeometry{BlockSize: 64, InodeRatio: 3, Version: fsVersionBitBitmap}
+
 type Inode struct {
 	IUid   int32
 	IGid   int32
@@ -41,19 +63,25 @@ type Inode struct {
 	ICtime [19]byte
 	IMtime [19]byte
 	IBlock [15]int32
-	IType  byte
+	IType  byte // '0' carpeta, '1' archivo, '2' enlace simbólico
 	IPerm  int32
+	ILinks int32 // Entradas de carpeta que apuntan al inodo; 0 en formatos anteriores
+}
+
+// FolderContent es una entrada (nombre, inodo) dentro de un bloque de carpeta
+type FolderContent struct {
+	BName  [12]byte
+	BInode int32
 }
 
+// FolderBlock contiene SBlockSize/16 entradas de carpeta
 type FolderBlock struct {
-	BContent [4]struct {
-		BName  [12]byte
-		BInode int32
-	}
+	BContent []FolderContent
 }
 
+// FileBlock contiene SBlockSize bytes de datos de archivo
 type FileBlock struct {
-	BContent [64]byte
+	BContent []byte
 }
 
 // Estructura para la sesión activa
@@ -62,92 +90,245 @@ type Session struct {
 	Username string
 	GroupID  int32
 	PartID   string
+	Cwd      string // Carpeta actual, desde la que se resuelven las rutas relativas
 }
 
 // Variable global para la sesión activa
 var currentSession *Session
 
-// readSuperblock lee el superbloque de una partición
-func readSuperblock(file *os.File, mp *MountedPartition) (Superblock, error) {
-	var sb Superblock
-
-	// Leer el MBR para obtener la posición inicial de la partición
+// getPartitionBounds busca una partición montada en el MBR o EBR y devuelve su inicio y tamaño
+func getPartitionBounds(file *os.File, mp *MountedPartition) (int32, int32, error) {
 	mbr, err := readMBR(file)
 	if err != nil {
-		return Superblock{}, fmt.Errorf("error al leer MBR: %v", err)
+		return 0, 0, fmt.Errorf("error al leer MBR: %v", err)
 	}
 
-	// Buscar la partición correspondiente en el MBR
-	var partStart int32
-	var partSize int32
-	found := false
 	for _, p := range mbr.MbrPartitions {
-		name := strings.Trim(string(p.PartName[:]), "\x00")
-		fmt.Printf("MBR PartName: %s, Expected: %s\n", name, mp.Name)
-		if name == mp.Name {
-			partStart = p.PartStart
-			partSize = p.PartSize
-			found = true
-			break
+		if p.PartStatus == '1' && strings.Trim(string(p.PartName[:]), "\x00") == mp.Name {
+			return p.PartStart, p.PartSize, nil
 		}
 	}
 
 	// Si no se encontró en el MBR, buscar en EBR para particiones lógicas
-	if !found {
-		for _, p := range mbr.MbrPartitions {
-			if p.PartType == 'E' {
-				currentPos := p.PartStart
-				for currentPos != -1 {
-					file.Seek(int64(currentPos), 0)
-					var ebr EBR
-					if err := binary.Read(file, binary.LittleEndian, &ebr); err != nil {
-						fmt.Printf("Error leyendo EBR en pos %d: %v\n", currentPos, err)
-						break
-					}
-					name := strings.Trim(string(ebr.PartName[:]), "\x00")
-					fmt.Printf("EBR PartName: %s, Expected: %s, Start: %d, Next: %d\n",
-						name, mp.Name, ebr.PartStart, ebr.PartNext)
-					if name == mp.Name && ebr.PartSize > 0 {
-						partStart = ebr.PartStart
-						partSize = ebr.PartSize
-						found = true
-						break
-					}
-					currentPos = ebr.PartNext
-				}
-			}
-			if found {
+	for _, p := range mbr.MbrPartitions {
+		if p.PartStatus != '1' || p.PartType != 'E' {
+			continue
+		}
+		visited := make(map[int32]bool)
+		currentPos := p.PartStart
+		for currentPos != -1 && !visited[currentPos] {
+			visited[currentPos] = true
+			ebr, err := readEBR(file, int64(currentPos))
+			if err != nil {
 				break
 			}
+			if strings.Trim(string(ebr.PartName[:]), "\x00") == mp.Name && ebr.PartSize > 0 {
+				return ebr.PartStart, ebr.PartSize, nil
+			}
+			currentPos = ebr.PartNext
 		}
 	}
 
-	if !found {
-		return Superblock{}, fmt.Errorf("partición %s no encontrada en MBR ni EBR", mp.Name)
+	return 0, 0, fmt.Errorf("partición %s no encontrada en MBR ni EBR", mp.Name)
+}
+
+// readSuperblock lee el superbloque principal de una partición. Si está dañado se
+// devuelve un error en lugar de la copia de respaldo: sus contadores pueden estar
+// desactualizados y el primer Commit la escribiría como principal.
+func readSuperblock(file *os.File, mp *MountedPartition) (Superblock, error) {
+	sb, backup, err := loadSuperblock(file, mp)
+	if err == nil && backup != nil {
+		return Superblock{}, fmt.Errorf("superbloque principal de %s dañado, use restoresb -id=%s o fsck -id=%s -repair", mp.Name, mp.ID, mp.ID)
+	}
+	return sb, err
+}
+
+// loadSuperblock lee el superbloque principal o, si es inválido, la copia de respaldo
+// más reciente; en ese caso también devuelve la copia usada
+func loadSuperblock(file *os.File, mp *MountedPartition) (Superblock, *sbBackup, error) {
+	var sb Superblock
+
+	partStart, partSize, err := getPartitionBounds(file, mp)
+	if err != nil {
+		return Superblock{}, nil, err
 	}
 
 	// Validar tamaño de partición
 	if partSize <= 0 {
-		return Superblock{}, fmt.Errorf("tamaño de partición %s es inválido: %d", mp.Name, partSize)
+		return Superblock{}, nil, fmt.Errorf("tamaño de partición %s es inválido: %d", mp.Name, partSize)
 	}
 
 	// Validar partStart
 	if partStart <= 0 {
-		return Superblock{}, fmt.Errorf("posición inicial de partición %s inválida: %d", mp.Name, partStart)
+		return Superblock{}, nil, fmt.Errorf("posición inicial de partición %s inválida: %d", mp.Name, partStart)
 	}
 
 	// Leer el superbloque desde la posición inicial de la partición
-	file.Seek(int64(partStart), 0)
-	if err := binary.Read(file, binary.LittleEndian, &sb); err != nil {
-		return Superblock{}, fmt.Errorf("error al leer superbloque: %v", err)
+	buf := make([]byte, binary.Size(Superblock{}))
+	if _, err := file.ReadAt(buf, int64(partStart)); err != nil {
+		return Superblock{}, nil, fmt.Errorf("error al leer superbloque: %v", err)
 	}
+	binary.Read(bytes.NewReader(buf), binary.LittleEndian, &sb)
 
 	// Validar SMagic para confirmar que es EXT2
 	if sb.SMagic != 0xEF53 {
-		return Superblock{}, fmt.Errorf("superbloque inválido para %s, SMagic=%x", mp.Name, sb.SMagic)
+		if backup, ok := newestBackup(readBackupSuperblocks(file, partStart, partSize)); ok {
+			return backup.SB, &backup, nil
+		}
+		return Superblock{}, nil, fmt.Errorf("superbloque inválido para %s, SMagic=%x", mp.Name, sb.SMagic)
+	}
+
+	// En superbloques de versiones anteriores los campos que no existían
+	// contienen bytes del bitmap de inodos y se leen como cero
+	if n := superblockDiskSize(&sb, partStart); n < len(buf) {
+		clear(buf[n:])
+		binary.Read(bytes.NewReader(buf), binary.LittleEndian, &sb)
+	}
+
+	return sb, nil, nil
+}
+
+// superblockDiskSize devuelve cuántos bytes ocupa el superbloque en disco.
+// El bitmap de inodos empieza justo después, así que su posición delata la versión.
+func superblockDiskSize(sb *Superblock, partStart int32) int {
+	size := int(sb.SBmInodeStart - partStart)
+	if full := binary.Size(Superblock{}); size <= 0 || size > full {
+		return full
+	}
+	return size
+}
+
+// writeSuperblock escribe el superbloque al inicio de la partición respetando su versión
+func writeSuperblock(file *os.File, partStart int32, sb *Superblock) error {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, sb); err != nil {
+		return err
+	}
+	data := buf.Bytes()[:superblockDiskSize(sb, partStart)]
+	_, err := file.WriteAt(data, int64(partStart))
+	return err
+}
+
+// stampMount registra en el superbloque la fecha de montaje (y suma un montaje) o la de
+// desmontaje. Una partición sin formatear o con el superbloque principal dañado se deja igual.
+func stampMount(file *os.File, mp *MountedPartition, mounting bool) error {
+	sb, backup, err := loadSuperblock(file, mp)
+	if err != nil || backup != nil {
+		return nil
+	}
+	partStart, _, err := getPartitionBounds(file, mp)
+	if err != nil {
+		return err
+	}
+	if mounting {
+		sb.SMtime = timestamp()
+		sb.SMntCount++
+	} else {
+		sb.SUmtime = timestamp()
 	}
+	return writeSuperblock(file, partStart, &sb)
+}
+
+// fsLayout calcula la distribución de las estructuras EXT2 dentro de una partición
+func fsLayout(partStart, partSize int32, geo fsGeometry) (Superblock, error) {
+	superblockSize := int32(binary.Size(Superblock{}))
+	inodeSize := geo.InodeSize
+	if inodeSize <= 0 {
+		inodeSize = int32(binary.Size(Inode{}))
+	}
+	blockSize := geo.BlockSize
+	if partSize <= superblockSize+sbBackupArea() {
+		return Superblock{}, fmt.Errorf("tamaño de partición %d es demasiado pequeño para superbloque %d", partSize, superblockSize)
+	}
+
+	// Cada inodo ocupa su entrada de bitmap + inodeSize; cada bloque su entrada de bitmap + blockSize.
+	// Con bitmaps de bits la entrada es 1/8 de byte, así que se ajusta hasta que todo quepa.
+	bitmapEntry := 1.0
+	if geo.Version == fsVersionBitBitmap {
+		bitmapEntry = 1.0 / 8
+	}
+	// Las copias de respaldo del superbloque quedan al final de la partición
+	available := int64(partSize - superblockSize - sbBackupArea())
+	used := func(inodes, blocks int32) int64 {
+		return int64(bitmapBytes(inodes, geo.Version)) + int64(bitmapBytes(blocks, geo.Version)) +
+			int64(inodes)*int64(inodeSize) + int64(blocks)*int64(blockSize)
+	}
+	var inodesCount, blocksCount int32
+	if geo.Inodes > 0 {
+		inodesCount = geo.Inodes
+		remaining := available - int64(bitmapBytes(inodesCount, geo.Version)) - int64(inodesCount)*int64(inodeSize)
+		blocksCount = int32(math.Floor(float64(remaining) / (bitmapEntry + float64(blockSize))))
+		for blocksCount > 0 && used(inodesCount, blocksCount) > available {
+			blocksCount--
+		}
+	} else {
+		n := float64(available) / (bitmapEntry*float64(1+geo.InodeRatio) + float64(inodeSize) + float64(geo.InodeRatio*blockSize))
+		inodesCount = int32(math.Floor(n))
+		for inodesCount > 0 && used(inodesCount, geo.InodeRatio*inodesCount) > available {
+			inodesCount--
+		}
+		blocksCount = geo.InodeRatio * inodesCount
+	}
+	if inodesCount < 2 || blocksCount < 2+geo.Reserved {
+		return Superblock{}, fmt.Errorf("no hay espacio suficiente para estructuras EXT2 (inodos=%d, bloques=%d)", inodesCount, blocksCount)
+	}
+
+	bmInodeStart := partStart + superblockSize
+	bmBlockStart := bmInodeStart + bitmapBytes(inodesCount, geo.Version)
+	inodeStart := bmBlockStart + bitmapBytes(blocksCount, geo.Version)
+	return Superblock{
+		SFilesystemType: 2,
+		SInodesCount:    inodesCount,
+		SBlocksCount:    blocksCount,
+		SMagic:          0xEF53,
+		SInodeSize:      inodeSize,
+		SBlockSize:      blockSize,
+		SReservedBlocks: geo.Reserved,
+		SVersion:        geo.Version,
+		SBmInodeStart:   bmInodeStart,
+		SBmBlockStart:   bmBlockStart,
+		SInodeStart:     inodeStart,
+		SBlockStart:     inodeStart + inodesCount*inodeSize,
+	}, nil
+}
 
-	return sb, nil
+// parseGeometry lee los parámetros -blocksize, -inodes, -inode-ratio y -reserved de mkfs
+func parseGeometry(params map[string]string) (fsGeometry, error) {
+	geo := defaultGeometry
+	if v, ok := params["blocksize"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 64 || n > 4096 || n&(n-1) != 0 {
+			return geo, fmt.Errorf("-blocksize debe ser una potencia de 2 entre 64 y 4096")
+		}
+		geo.BlockSize = int32(n)
+	}
+	_, hasInodes := params["inodes"]
+	_, hasRatio := params["inode-ratio"]
+	if hasInodes && hasRatio {
+		return geo, fmt.Errorf("no se pueden especificar -inodes e -inode-ratio juntos")
+	}
+	if hasInodes {
+		n, err := strconv.Atoi(params["inodes"])
+		if err != nil || n < 2 {
+			return geo, fmt.Errorf("-inodes debe ser un entero mayor o igual a 2")
+		}
+		geo.Inodes = int32(n)
+	}
+	if hasRatio {
+		n, err := strconv.Atoi(params["inode-ratio"])
+		if err != nil || n < 1 {
+			return geo, fmt.Errorf("-inode-ratio debe ser un entero positivo")
+		}
+		geo.InodeRatio = int32(n)
+	}
+	if v, ok := params["reserved"]; ok {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			return geo, fmt.Errorf("-reserved debe ser un entero no negativo")
+		}
+		geo.Reserved = int32(n)
+	}
+	return geo, nil
 }
 
 // MKFS: Formatea una partición con EXT2
@@ -157,6 +338,30 @@ func mkfs(params map[string]string) string {
 		return "Error: Parámetro -id es obligatorio"
 	}
 
+	// full limpia toda la partición, fast solo escribe las estructuras iniciales
+	formatType := strings.ToLower(params["type"])
+	if formatType == "" {
+		formatType = "full"
+	}
+	if formatType != "full" && formatType != "fast" {
+		return fmt.Sprintf("Error: Valor de -type no válido: %s (use full o fast)", params["type"])
+	}
+
+	geo, err := parseGeometry(params)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	// first reutiliza los huecos más bajos, next continúa después de la última asignación
+	allocFit := int32(allocFirstFit)
+	switch strings.ToLower(params["alloc"]) {
+	case "", "first":
+	case "next":
+		allocFit = allocNextFit
+	default:
+		return fmt.Sprintf("Error: Valor de -alloc no válido: %s (use first o next)", params["alloc"])
+	}
+
 	var mp *MountedPartition
 	for _, p := range mountedPartitions {
 		if p.ID == id {
@@ -223,72 +428,51 @@ func mkfs(params map[string]string) string {
 		return fmt.Sprintf("Error: Partición %s no encontrada en MBR ni EBR", mp.Name)
 	}
 
-	partSize := part.PartSize
-	superblockSize := int32(binary.Size(Superblock{}))
-	inodeSize := int32(binary.Size(Inode{}))
-	blockSize := int32(64)
-	if partSize <= superblockSize {
-		return fmt.Sprintf("Error: Tamaño de partición %d es demasiado pequeño para superbloque %d", partSize, superblockSize)
-	}
-
-	n := float64(partSize-superblockSize) / float64(1+3+inodeSize+3*blockSize)
-	numStructs := int32(math.Floor(n))
-	if numStructs <= 0 {
-		return fmt.Sprintf("Error: No hay espacio suficiente para estructuras EXT2 (numStructs=%d)", numStructs)
+	sb, err := fsLayout(part.PartStart, part.PartSize, geo)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 
-	// Inicializar superbloque
+	// Inicializar superbloque: los inodos 0-1 y bloques 0-1 son la raíz y users.txt,
+	// seguidos por los bloques reservados
 	fecha := time.Now().Format("2006-01-02 15:04:05")
-	sb := Superblock{
-		SFilesystemType:  2,
-		SInodesCount:     numStructs,
-		SBlocksCount:     3 * numStructs,
-		SFreeInodesCount: numStructs - 2,
-		SFreeBlocksCount: 3*numStructs - 2,
-		SMagic:           0xEF53,
-		SInodeSize:       inodeSize,
-		SBlockSize:       blockSize,
-		SBmInodeStart:    part.PartStart + superblockSize,
-		SBmBlockStart:    part.PartStart + superblockSize + numStructs,
-		SInodeStart:      part.PartStart + superblockSize + numStructs + 3*numStructs,
-		SBlockStart:      part.PartStart + superblockSize + numStructs + 3*numStructs + numStructs*inodeSize,
-		SFirstIno:        2,
-		SFirstBlo:        2,
-	}
+	sb.SFreeInodesCount = sb.SInodesCount - 2
+	sb.SFreeBlocksCount = sb.SBlocksCount - 2 - sb.SReservedBlocks
+	sb.SFirstIno = 2
+	sb.SFirstBlo = 2 + sb.SReservedBlocks
+	sb.SAllocFit = allocFit
 	copy(sb.SMtime[:], fecha)
 	copy(sb.SUmtime[:], fecha)
 	sb.SMntCount = 1
 
+	if formatType == "full" {
+		if err := zeroRange(file, int64(part.PartStart), int64(part.PartSize)); err != nil {
+			return fmt.Sprintf("Error al limpiar la partición: %v", err)
+		}
+	}
+
 	// Escribir superbloque
-	file.Seek(int64(part.PartStart), 0)
-	if err := binary.Write(file, binary.LittleEndian, &sb); err != nil {
+	if err := writeSuperblock(file, part.PartStart, &sb); err != nil {
 		return fmt.Sprintf("Error al escribir superbloque: %v", err)
 	}
+	if err := writeBackupSuperblocks(file, part.PartStart, part.PartSize, &sb); err != nil {
+		return fmt.Sprintf("Error al escribir copias del superbloque: %v", err)
+	}
 
 	// Inicializar bitmaps
-	bitmapInodes := make([]byte, numStructs)
-	bitmapBlocks := make([]byte, 3*numStructs)
-	bitmapInodes[0], bitmapInodes[1] = 1, 1
-	bitmapBlocks[0], bitmapBlocks[1] = 1, 1
-	file.Seek(int64(sb.SBmInodeStart), 0)
-	file.Write(bitmapInodes)
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	file.Write(bitmapBlocks)
+	bitmapInodes := newBitmap(sb.SBmInodeStart, sb.SInodesCount, sb.SVersion)
+	bitmapBlocks := newBitmap(sb.SBmBlockStart, sb.SBlocksCount, sb.SVersion)
+	bitmapInodes.SetRange(0, 2)
+	bitmapBlocks.SetRange(0, sb.SFirstBlo)
+	bitmapInodes.write(file)
+	bitmapBlocks.write(file)
 
 	// Crear inodo raíz
-	inodeRoot := Inode{
-		IUid:  1,
-		IGid:  1,
-		ISize: 0,
-		IType: '0',
-		IPerm: 777,
-	}
+	inodeRoot := newInode('0', 777)
+	inodeRoot.IUid, inodeRoot.IGid = 1, 1
 	inodeRoot.IBlock[0] = 0
-	copy(inodeRoot.IAtime[:], fecha)
-	copy(inodeRoot.ICtime[:], fecha)
-	copy(inodeRoot.IMtime[:], fecha)
 
-	folderBlock := FolderBlock{}
+	folderBlock := newFolderBlock(sb)
 	copy(folderBlock.BContent[0].BName[:], ".")
 	folderBlock.BContent[0].BInode = 0
 	copy(folderBlock.BContent[1].BName[:], "..")
@@ -298,19 +482,12 @@ func mkfs(params map[string]string) string {
 
 	// Crear inodo para users.txt
 	usersContent := "1,G,root\n1,U,root,root,123\n"
-	inodeUsers := Inode{
-		IUid:  1,
-		IGid:  1,
-		ISize: int32(len(usersContent)),
-		IType: '1',
-		IPerm: 777,
-	}
+	inodeUsers := newInode('1', 777)
+	inodeUsers.IUid, inodeUsers.IGid = 1, 1
+	inodeUsers.ISize = int32(len(usersContent))
 	inodeUsers.IBlock[0] = 1
-	copy(inodeUsers.IAtime[:], fecha)
-	copy(inodeUsers.ICtime[:], fecha)
-	copy(inodeUsers.IMtime[:], fecha)
 
-	fileBlock := FileBlock{}
+	fileBlock := newFileBlock(sb)
 	copy(fileBlock.BContent[:], usersContent)
 
 	// Escribir inodos
@@ -319,11 +496,10 @@ func mkfs(params map[string]string) string {
 	binary.Write(file, binary.LittleEndian, &inodeUsers)
 
 	// Escribir bloques
-	file.Seek(int64(sb.SBlockStart), 0)
-	binary.Write(file, binary.LittleEndian, &folderBlock)
-	binary.Write(file, binary.LittleEndian, &fileBlock)
+	writeFolderBlock(file, sb, 0, &folderBlock)
+	writeFileBlock(file, sb, 1, &fileBlock)
 
-	return fmt.Sprintf("Partición %s formateada exitosamente", id)
+	return fmt.Sprintf("Partición %s formateada exitosamente (%s)", id, formatType)
 }
 
 // CAT: Muestra el contenido de un archivo
@@ -333,6 +509,10 @@ func cat(params map[string]string) string {
 	if !hasFile || !hasID {
 		return "Error: Parámetros -file y -id son obligatorios"
 	}
+	encoding := strings.ToLower(params["encoding"])
+	if encoding != "" && encoding != "text" && encoding != "base64" && encoding != "hex" {
+		return fmt.Sprintf("Error: Valor de -encoding no válido: %s (use text, base64 o hex)", params["encoding"])
+	}
 
 	if currentSession == nil {
 		return "Error: No hay sesión activa"
@@ -367,48 +547,11 @@ func cat(params map[string]string) string {
 		return fmt.Sprintf("Error: %v", err)
 	}
 
-	// Navegar hasta la carpeta padre
-	parentPath := pathParts[:len(pathParts)-1]
+	// Buscar el archivo siguiendo los enlaces simbólicos
 	fileName := pathParts[len(pathParts)-1]
-	currentInode := int32(0) // Raíz por defecto
-	if len(parentPath) > 0 {
-		currentInode, err = navigateToParent(f, sb, parentPath)
-		if err != nil {
-			return fmt.Sprintf("Error al navegar a la carpeta padre: %v", err)
-		}
-	}
-
-	// Buscar el archivo
-	inode, err := readInode(f, sb, currentInode)
+	fileInode, err := resolvePath(f, sb, pathParts, true)
 	if err != nil {
-		return fmt.Sprintf("Error al leer inodo: %v", err)
-	}
-	if inode.IType != '0' {
-		return fmt.Sprintf("Error: %s no es una carpeta", file)
-	}
-
-	var fileInode int32 = -1
-	for _, blockIndex := range inode.IBlock {
-		if blockIndex == -1 {
-			continue
-		}
-		folderBlock, err := readFolderBlock(f, sb, blockIndex)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bloque: %v", err)
-		}
-		for _, content := range folderBlock.BContent {
-			name := strings.Trim(string(content.BName[:]), "\x00")
-			if name == fileName {
-				fileInode = content.BInode
-				break
-			}
-		}
-		if fileInode != -1 {
-			break
-		}
-	}
-	if fileInode == -1 {
-		return fmt.Sprintf("Error: Archivo %s no encontrado", fileName)
+		return fmt.Sprintf("Error: Archivo %s no encontrado: %v", fileName, err)
 	}
 
 	// Leer el inodo del archivo
@@ -426,19 +569,21 @@ func cat(params map[string]string) string {
 	}
 
 	// Leer contenido
-	var content strings.Builder
-	for _, blockIndex := range fileInodeData.IBlock {
-		if blockIndex == -1 {
-			continue
-		}
-		fileBlock, err := readFileBlock(f, sb, blockIndex)
-		if err != nil {
-			return fmt.Sprintf("Error al leer bloque de archivo: %v", err)
-		}
-		content.Write(fileBlock.BContent[:])
+	content, err := readFileContent(f, sb, fileInodeData)
+	if err != nil {
+		return fmt.Sprintf("Error al leer bloque de archivo: %v", err)
+	}
+	if err = touchInode(f, sb, fileInode, true, false); err != nil {
+		return fmt.Sprintf("Error: %v", err)
 	}
 
-	return strings.Trim(content.String(), "\x00")
+	switch encoding {
+	case "base64":
+		return base64.StdEncoding.EncodeToString(content)
+	case "hex":
+		return hex.EncodeToString(content)
+	}
+	return string(content)
 }
 
 func hasReadPermission(inode Inode, session *Session) bool {
@@ -518,6 +663,7 @@ func login(params map[string]string) string {
 				Username: user,
 				GroupID:  1,
 				PartID:   id,
+				Cwd:      "/",
 			}
 			return fmt.Sprintf("Sesión iniciada para %s", user)
 		}
@@ -536,6 +682,70 @@ func logout(params map[string]string) string {
 	return fmt.Sprintf("Sesión cerrada para %s", username)
 }
 
+// CD: Cambia la carpeta actual de la sesión.
+func cd(params map[string]string) string {
+	path, hasPath := params["path"]
+	if !hasPath {
+		path = "/"
+	}
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == currentSession.PartID {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", currentSession.PartID)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	pathParts, err := splitPath(path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	index, err := resolvePath(file, sb, pathParts, true)
+	if err != nil {
+		return fmt.Sprintf("Error: %s no encontrado: %v", path, err)
+	}
+	inode, err := readInode(file, sb, index)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", index, err)
+	}
+	if inode.IType != '0' {
+		return fmt.Sprintf("Error: %s no es una carpeta", path)
+	}
+	if !hasReadPermission(inode, currentSession) {
+		return fmt.Sprintf("Error: Permiso denegado para entrar a %s", path)
+	}
+
+	currentSession.Cwd = "/" + strings.Join(pathParts, "/")
+	return currentSession.Cwd
+}
+
+// PWD: Muestra la carpeta actual de la sesión.
+func pwd(params map[string]string) string {
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+	return currentSession.Cwd
+}
+
 // MKGRP: Crea un grupo
 func mkgrp(params map[string]string) string {
 	name, hasName := params["name"]
@@ -602,7 +812,7 @@ func mkgrp(params map[string]string) string {
 
 	newLine := fmt.Sprintf("%d,G,%s\n", maxGID+1, name)
 	usersContent += newLine
-	if err := writeUsersTxt(file, sb, usersContent); err != nil {
+	if err := writeUsersTxt(file, mp, sb, usersContent); err != nil {
 		return fmt.Sprintf("Error al escribir users.txt: %v", err)
 	}
 
@@ -673,7 +883,7 @@ func rmgrp(params map[string]string) string {
 		return fmt.Sprintf("Error: El grupo %s no existe", name)
 	}
 
-	if err := writeUsersTxt(file, sb, newContent.String()); err != nil {
+	if err := writeUsersTxt(file, mp, sb, newContent.String()); err != nil {
 		return fmt.Sprintf("Error al escribir users.txt: %v", err)
 	}
 
@@ -758,7 +968,7 @@ func mkusr(params map[string]string) string {
 
 	newLine := fmt.Sprintf("%d,U,%s,%s,%s\n", maxUID+1, grp, user, pass)
 	usersContent += newLine
-	if err := writeUsersTxt(file, sb, usersContent); err != nil {
+	if err := writeUsersTxt(file, mp, sb, usersContent); err != nil {
 		return fmt.Sprintf("Error al escribir users.txt: %v", err)
 	}
 
@@ -829,7 +1039,7 @@ func rmusr(params map[string]string) string {
 		return fmt.Sprintf("Error: El usuario %s no existe", user)
 	}
 
-	if err := writeUsersTxt(file, sb, newContent.String()); err != nil {
+	if err := writeUsersTxt(file, mp, sb, newContent.String()); err != nil {
 		return fmt.Sprintf("Error al escribir users.txt: %v", err)
 	}
 
@@ -917,7 +1127,7 @@ func chgrp(params map[string]string) string {
 		return fmt.Sprintf("Error: El usuario %s no existe", user)
 	}
 
-	if err := writeUsersTxt(file, sb, newContent.String()); err != nil {
+	if err := writeUsersTxt(file, mp, sb, newContent.String()); err != nil {
 		return fmt.Sprintf("Error al escribir users.txt: %v", err)
 	}
 
@@ -925,33 +1135,80 @@ func chgrp(params map[string]string) string {
 }
 
 // Funciones auxiliares
+// readInode lee un inodo de la tabla de inodos. Los inodos de formatos anteriores
+// ocupan SInodeSize bytes y los campos que no existían se leen como cero.
 func readInode(file *os.File, sb Superblock, inodeIndex int32) (Inode, error) {
 	var inode Inode
-	file.Seek(int64(sb.SInodeStart+inodeIndex*sb.SInodeSize), 0)
-	if err := binary.Read(file, binary.LittleEndian, &inode); err != nil {
+	buf := make([]byte, binary.Size(Inode{}))
+	n := int(sb.SInodeSize)
+	if n <= 0 || n > len(buf) {
+		n = len(buf)
+	}
+	if _, err := file.ReadAt(buf[:n], int64(sb.SInodeStart+inodeIndex*sb.SInodeSize)); err != nil {
+		return Inode{}, err
+	}
+	if err := binary.Read(bytes.NewReader(buf), binary.LittleEndian, &inode); err != nil {
 		return Inode{}, err
 	}
 	return inode, nil
 }
 
+// writeInode escribe un inodo en la tabla de inodos respetando el tamaño de inodo de la partición
+func writeInode(file *os.File, sb Superblock, inodeIndex int32, inode *Inode) error {
+	var buf bytes.Buffer
+	if err := binary.Write(&buf, binary.LittleEndian, inode); err != nil {
+		return err
+	}
+	data := buf.Bytes()
+	if n := int(sb.SInodeSize); n > 0 && n < len(data) {
+		data = data[:n]
+	}
+	_, err := file.WriteAt(data, int64(sb.SInodeStart+inodeIndex*sb.SInodeSize))
+	return err
+}
+
+// newFolderBlock crea un bloque de carpeta vacío del tamaño de bloque de la partición
+func newFolderBlock(sb Superblock) FolderBlock {
+	block := FolderBlock{BContent: make([]FolderContent, sb.SBlockSize/folderEntrySize)}
+	for i := range block.BContent {
+		block.BContent[i].BInode = -1
+	}
+	return block
+}
+
+// newFileBlock crea un bloque de archivo vacío del tamaño de bloque de la partición
+func newFileBlock(sb Superblock) FileBlock {
+	return FileBlock{BContent: make([]byte, sb.SBlockSize)}
+}
+
 func readFolderBlock(file *os.File, sb Superblock, blockIndex int32) (FolderBlock, error) {
-	var block FolderBlock
+	block := FolderBlock{BContent: make([]FolderContent, sb.SBlockSize/folderEntrySize)}
 	file.Seek(int64(sb.SBlockStart+blockIndex*sb.SBlockSize), 0)
-	if err := binary.Read(file, binary.LittleEndian, &block); err != nil {
+	if err := binary.Read(file, binary.LittleEndian, block.BContent); err != nil {
 		return FolderBlock{}, err
 	}
 	return block, nil
 }
 
 func readFileBlock(file *os.File, sb Superblock, blockIndex int32) (FileBlock, error) {
-	var block FileBlock
+	block := newFileBlock(sb)
 	file.Seek(int64(sb.SBlockStart+blockIndex*sb.SBlockSize), 0)
-	if err := binary.Read(file, binary.LittleEndian, &block); err != nil {
+	if err := binary.Read(file, binary.LittleEndian, block.BContent); err != nil {
 		return FileBlock{}, err
 	}
 	return block, nil
 }
 
+func writeFolderBlock(file *os.File, sb Superblock, blockIndex int32, block *FolderBlock) error {
+	file.Seek(int64(sb.SBlockStart+blockIndex*sb.SBlockSize), 0)
+	return binary.Write(file, binary.LittleEndian, block.BContent)
+}
+
+func writeFileBlock(file *os.File, sb Superblock, blockIndex int32, block *FileBlock) error {
+	file.Seek(int64(sb.SBlockStart+blockIndex*sb.SBlockSize), 0)
+	return binary.Write(file, binary.LittleEndian, block.BContent)
+}
+
 func readUsersTxt(file *os.File, sb Superblock) (string, error) {
 	inode, err := readInode(file, sb, 1)
 	if err != nil {
@@ -960,47 +1217,63 @@ func readUsersTxt(file *os.File, sb Superblock) (string, error) {
 	if inode.IType != '1' {
 		return "", fmt.Errorf("inodo de users.txt inválido")
 	}
-	var content strings.Builder
-	bytesRemaining := inode.ISize
-	blockSize := sb.SBlockSize // 64 bytes
-
 	fmt.Printf("Leyendo users.txt: iSize=%d, IBlock=%v\n", inode.ISize, inode.IBlock)
 
-	for _, blockIndex := range inode.IBlock {
-		if blockIndex == -1 {
+	content, err := readFileContent(file, sb, inode)
+	if err != nil {
+		return "", err
+	}
+	return string(content), nil
+}
+
+// userAndGroupNames devuelve los nombres de usuarios y grupos de users.txt indexados por su id
+func userAndGroupNames(usersContent string) (users, groups map[int32]string) {
+	users, groups = map[int32]string{}, map[int32]string{}
+	for _, line := range strings.Split(usersContent, "\n") {
+		parts := strings.Split(line, ",")
+		if len(parts) < 3 {
 			continue
 		}
-		if bytesRemaining <= 0 {
-			break
+		id, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+		if err != nil {
+			continue
+		}
+		switch strings.TrimSpace(parts[1]) {
+		case "G":
+			groups[int32(id)] = strings.TrimSpace(parts[2])
+		case "U":
+			if len(parts) >= 4 {
+				users[int32(id)] = strings.TrimSpace(parts[3])
+			}
 		}
+	}
+	return users, groups
+}
 
+// readFileContent devuelve exactamente los ISize bytes de un archivo, sin interpretar su contenido
+func readFileContent(file *os.File, sb Superblock, inode Inode) ([]byte, error) {
+	content := make([]byte, 0, inode.ISize)
+	for _, blockIndex := range inode.IBlock {
+		remaining := int(inode.ISize) - len(content)
+		if remaining <= 0 {
+			break
+		}
+		if blockIndex == -1 {
+			continue
+		}
 		fileBlock, err := readFileBlock(file, sb, blockIndex)
 		if err != nil {
-			return "", err
+			return nil, fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
 		}
-
-		// Determinar cuántos bytes leer de este bloque
-		bytesToRead := blockSize
-		if bytesRemaining < blockSize {
-			bytesToRead = bytesRemaining
+		if remaining > len(fileBlock.BContent) {
+			remaining = len(fileBlock.BContent)
 		}
-
-		// Leer solo los bytes necesarios y convertirlos a string
-		blockContent := fileBlock.BContent[:bytesToRead]
-		for i := int32(0); i < bytesToRead; i++ {
-			if blockContent[i] == 0 {
-				bytesToRead = i
-				break
-			}
-		}
-		content.Write(blockContent[:bytesToRead])
-		bytesRemaining -= bytesToRead
+		content = append(content, fileBlock.BContent[:remaining]...)
 	}
-
-	return content.String(), nil
+	return content, nil
 }
 
-func writeUsersTxt(file *os.File, sb Superblock, content string) error {
+func writeUsersTxt(file *os.File, mp *MountedPartition, sb Superblock, content string) error {
 	inode, err := readInode(file, sb, 1)
 	if err != nil {
 		return err
@@ -1009,56 +1282,37 @@ func writeUsersTxt(file *os.File, sb Superblock, content string) error {
 		return fmt.Errorf("inodo de users.txt inválido")
 	}
 
-	numBlocks := int32(math.Ceil(float64(len(content)) / 64.0))
+	numBlocks := int32(math.Ceil(float64(len(content)) / float64(sb.SBlockSize)))
 	if numBlocks > 15 {
 		return fmt.Errorf("contenido de users.txt excede capacidad")
 	}
 
-	// Leer bitmap de bloques
-	bitmapBlocks := make([]byte, sb.SBlocksCount)
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	_, err = file.Read(bitmapBlocks)
+	alloc, err := newAllocator(file, mp, &sb)
 	if err != nil {
-		return fmt.Errorf("error al leer bitmap de bloques: %v", err)
-	}
-
-	// Liberar bloques anteriores
-	for i, blockIndex := range inode.IBlock {
-		if blockIndex != -1 && blockIndex < sb.SBlocksCount {
-			bitmapBlocks[blockIndex] = 0
-			inode.IBlock[i] = -1
-		}
+		return err
 	}
 
-	// Asignar nuevos bloques
-	currentBlock := int32(2) // Comenzar después de los bloques iniciales
-	for i := int32(0); i < numBlocks; i++ {
-		for currentBlock < sb.SBlocksCount && bitmapBlocks[currentBlock] == 1 {
-			currentBlock++
-		}
-		if currentBlock >= sb.SBlocksCount {
-			return fmt.Errorf("no hay bloques libres")
-		}
-		inode.IBlock[i] = currentBlock
-		bitmapBlocks[currentBlock] = 1
+	// Reemplazar los bloques anteriores por los nuevos
+	alloc.FreeInodeBlocks(&inode)
+	newBlocks, err := alloc.AllocBlocks(numBlocks)
+	if err != nil {
+		alloc.Rollback()
+		return err
 	}
+	copy(inode.IBlock[:], newBlocks)
 
 	// Escribir bloques con contenido
 	contentBytes := []byte(content)
-	for i := int32(0); i < numBlocks; i++ {
-		var block FileBlock
-		for j := range block.BContent {
-			block.BContent[j] = 0
-		}
-		start := i * 64
-		end := start + 64
+	for i, blockIndex := range newBlocks {
+		block := newFileBlock(sb)
+		start := int32(i) * sb.SBlockSize
+		end := start + sb.SBlockSize
 		if end > int32(len(contentBytes)) {
 			end = int32(len(contentBytes))
 		}
 		copy(block.BContent[:], contentBytes[start:end])
-		file.Seek(int64(sb.SBlockStart+inode.IBlock[i]*sb.SBlockSize), 0)
-		if err := binary.Write(file, binary.LittleEndian, &block); err != nil {
-			return fmt.Errorf("error al escribir bloque %d: %v", inode.IBlock[i], err)
+		if err := writeFileBlock(file, sb, blockIndex, &block); err != nil {
+			return fmt.Errorf("error al escribir bloque %d: %v", blockIndex, err)
 		}
 	}
 
@@ -1066,30 +1320,9 @@ func writeUsersTxt(file *os.File, sb Superblock, content string) error {
 	inode.ISize = int32(len(content))
 	fecha := time.Now().Format("2006-01-02 15:04:05")
 	copy(inode.IMtime[:], fecha)
-	file.Seek(int64(sb.SInodeStart+sb.SInodeSize), 0)
-	if err := binary.Write(file, binary.LittleEndian, &inode); err != nil {
+	if err := writeInode(file, sb, 1, &inode); err != nil {
 		return fmt.Errorf("error al escribir inodo: %v", err)
 	}
 
-	// Escribir bitmap de bloques
-	file.Seek(int64(sb.SBmBlockStart), 0)
-	if _, err := file.Write(bitmapBlocks); err != nil {
-		return fmt.Errorf("error al escribir bitmap de bloques: %v", err)
-	}
-
-	// Actualizar superbloque
-	file.Seek(int64(sb.SBmInodeStart-sb.SInodeSize), 0)
-	var sbUpdated Superblock
-	if err := binary.Read(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Errorf("error al leer superbloque para actualizar: %v", err)
-	}
-	sbUpdated.SFreeBlocksCount = sb.SBlocksCount - numBlocks
-	file.Seek(int64(sb.SBmInodeStart-sb.SInodeSize), 0)
-	if err := binary.Write(file, binary.LittleEndian, &sbUpdated); err != nil {
-		return fmt.Errorf("error al escribir superbloque: %v", err)
-	}
-
-	fmt.Printf("Escrito users.txt: iSize=%d, IBlock=%v, Content=%s\n", inode.ISize, inode.IBlock, content)
-
-	return nil
+	return alloc.Commit()
 }
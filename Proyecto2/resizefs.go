@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// RESIZEFS: Amplía el sistema de archivos de una partición para ocupar todo su tamaño actual.
+func resizefs(params map[string]string) string {
+	id, hasID := params["id"]
+	if !hasID {
+		return "Error: Parámetro -id es obligatorio"
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	partStart, partSize, err := getPartitionBounds(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	// Conservar la geometría con la que se formateó la partición
+	geo := fsGeometry{BlockSize: sb.SBlockSize, InodeRatio: sb.SBlocksCount / sb.SInodesCount, Reserved: sb.SReservedBlocks, Version: sb.SVersion, InodeSize: sb.SInodeSize}
+	if geo.InodeRatio < 1 {
+		geo.InodeRatio = 1
+	}
+	layout, err := fsLayout(partStart, partSize, geo)
+	if err == nil && (layout.SInodesCount < sb.SInodesCount || layout.SBlocksCount < sb.SBlocksCount) {
+		// La proporción no es exacta (ej. -inodes fijo): crecer solo en bloques
+		geo.Inodes = sb.SInodesCount
+		layout, err = fsLayout(partStart, partSize, geo)
+	}
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if layout.SInodeSize != sb.SInodeSize {
+		return "Error: La geometría del sistema de archivos no coincide con la actual, no se puede redimensionar"
+	}
+	if layout.SInodesCount <= sb.SInodesCount && layout.SBlocksCount <= sb.SBlocksCount {
+		return fmt.Sprintf("El sistema de archivos de %s ya ocupa toda la partición", id)
+	}
+
+	// Reubicar de atrás hacia adelante: bloques, inodos, bitmap de bloques y bitmap de inodos
+	// (este último solo se mueve si el superbloque era de la versión anterior).
+	// Los índices de inodos y bloques no cambian, solo su posición absoluta.
+	blocksSize := int64(sb.SBlocksCount) * int64(sb.SBlockSize)
+	if err := copyRange(file, int64(sb.SBlockStart), int64(layout.SBlockStart), blocksSize); err != nil {
+		return fmt.Sprintf("Error al mover bloques: %v", err)
+	}
+	inodesSize := int64(sb.SInodesCount) * int64(sb.SInodeSize)
+	if err := copyRange(file, int64(sb.SInodeStart), int64(layout.SInodeStart), inodesSize); err != nil {
+		return fmt.Sprintf("Error al mover tabla de inodos: %v", err)
+	}
+	oldInodeBitmap := int64(bitmapBytes(sb.SInodesCount, sb.SVersion))
+	oldBlockBitmap := int64(bitmapBytes(sb.SBlocksCount, sb.SVersion))
+	if err := copyRange(file, int64(sb.SBmBlockStart), int64(layout.SBmBlockStart), oldBlockBitmap); err != nil {
+		return fmt.Sprintf("Error al mover bitmap de bloques: %v", err)
+	}
+	if err := copyRange(file, int64(sb.SBmInodeStart), int64(layout.SBmInodeStart), oldInodeBitmap); err != nil {
+		return fmt.Sprintf("Error al mover bitmap de inodos: %v", err)
+	}
+
+	// Las estructuras nuevas quedan libres
+	newInodes := int64(layout.SInodesCount - sb.SInodesCount)
+	newBlocks := int64(layout.SBlocksCount - sb.SBlocksCount)
+	newInodeBitmap := int64(bitmapBytes(layout.SInodesCount, sb.SVersion))
+	newBlockBitmap := int64(bitmapBytes(layout.SBlocksCount, sb.SVersion))
+	if err := zeroRange(file, int64(layout.SBmInodeStart)+oldInodeBitmap, newInodeBitmap-oldInodeBitmap); err != nil {
+		return fmt.Sprintf("Error al limpiar bitmap de inodos: %v", err)
+	}
+	if err := zeroRange(file, int64(layout.SBmBlockStart)+oldBlockBitmap, newBlockBitmap-oldBlockBitmap); err != nil {
+		return fmt.Sprintf("Error al limpiar bitmap de bloques: %v", err)
+	}
+	if err := zeroRange(file, int64(layout.SInodeStart)+inodesSize, newInodes*int64(sb.SInodeSize)); err != nil {
+		return fmt.Sprintf("Error al limpiar tabla de inodos: %v", err)
+	}
+
+	oldInodes, oldBlocks := sb.SInodesCount, sb.SBlocksCount
+	sb.SInodesCount = layout.SInodesCount
+	sb.SBlocksCount = layout.SBlocksCount
+	sb.SFreeInodesCount += int32(newInodes)
+	sb.SFreeBlocksCount += int32(newBlocks)
+	sb.SBmInodeStart = layout.SBmInodeStart
+	sb.SBmBlockStart = layout.SBmBlockStart
+	sb.SInodeStart = layout.SInodeStart
+	sb.SBlockStart = layout.SBlockStart
+
+	if err := writeSuperblock(file, partStart, &sb); err != nil {
+		return fmt.Sprintf("Error al escribir superbloque: %v", err)
+	}
+	if err := writeBackupSuperblocks(file, partStart, partSize, &sb); err != nil {
+		return fmt.Sprintf("Error al escribir copias del superbloque: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+
+	return fmt.Sprintf("Sistema de archivos de %s ampliado: inodos %d -> %d, bloques %d -> %d",
+		id, oldInodes, sb.SInodesCount, oldBlocks, sb.SBlocksCount)
+}
@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Las copias de respaldo del superbloque ocupan los últimos bytes de la partición.
+// Cada ranura guarda el superbloque y, justo después, la posición de la partición al
+// momento de escribirla (para tolerar que defrag mueva la partición) y la fecha de
+// escritura en segundos, 4 bytes cada una, seguidas de un número de generación de 2 bytes
+// que ordena las copias escritas en el mismo segundo.
+const (
+	sbBackupCount = 2
+	sbBackupSlot  = 128
+)
+
+// sbBackupTrailer devuelve dónde empieza la marca de posición y fecha dentro de la ranura
+func sbBackupTrailer() int {
+	return binary.Size(Superblock{})
+}
+
+// sbBackup es una copia de respaldo leída desde el disco
+type sbBackup struct {
+	Slot    int // 1 = la más cercana al final de la partición
+	SB      Superblock
+	Written time.Time
+	Gen     uint16
+}
+
+// newerThan indica si la copia b se escribió después que other
+func (b sbBackup) newerThan(other sbBackup) bool {
+	if !b.Written.Equal(other.Written) {
+		return b.Written.After(other.Written)
+	}
+	return b.Gen > other.Gen
+}
+
+// sbBackupArea devuelve los bytes reservados al final de la partición para las copias
+func sbBackupArea() int32 {
+	return sbBackupCount * sbBackupSlot
+}
+
+// sbBackupOffset devuelve la posición absoluta de la ranura slot (1..sbBackupCount)
+func sbBackupOffset(partStart, partSize int32, slot int) int64 {
+	return int64(partStart) + int64(partSize) - int64(slot)*sbBackupSlot
+}
+
+// hasBackupArea indica si el área de datos deja espacio libre para las copias.
+// Los sistemas formateados antes de existir las copias pueden ocupar toda la partición.
+func hasBackupArea(sb Superblock, partStart, partSize int32) bool {
+	dataEnd := int64(sb.SBlockStart) + int64(sb.SBlocksCount)*int64(sb.SBlockSize)
+	return dataEnd <= sbBackupOffset(partStart, partSize, sbBackupCount)
+}
+
+// writeBackupSuperblocks escribe sb en todas las ranuras de respaldo de la partición. Se usa
+// al crear o cambiar la geometría del sistema de archivos, cuando las copias anteriores ya
+// no sirven; las actualizaciones posteriores usan updateBackupSuperblock.
+func writeBackupSuperblocks(file *os.File, partStart, partSize int32, sb *Superblock) error {
+	for k := 1; k <= sbBackupCount; k++ {
+		if err := writeBackupSlot(file, partStart, partSize, k, sb, 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateBackupSuperblock escribe sb en las ranuras sin copia válida o, si todas son válidas,
+// solo en la más antigua, para que la otra conserve la copia buena anterior. Devuelve las
+// ranuras escritas.
+func updateBackupSuperblock(file *os.File, partStart, partSize int32, sb *Superblock) ([]int, error) {
+	valid := map[int]sbBackup{}
+	for _, b := range readBackupSuperblocks(file, partStart, partSize) {
+		valid[b.Slot] = b
+	}
+	var slots []int
+	oldest := 0
+	var gen uint16
+	for k := 1; k <= sbBackupCount; k++ {
+		b, ok := valid[k]
+		if !ok {
+			slots = append(slots, k)
+			continue
+		}
+		if oldest == 0 || valid[oldest].newerThan(b) {
+			oldest = k
+		}
+		gen = max(gen, b.Gen+1)
+	}
+	if len(slots) == 0 {
+		slots = []int{oldest}
+	}
+	for _, k := range slots {
+		if err := writeBackupSlot(file, partStart, partSize, k, sb, gen); err != nil {
+			return nil, err
+		}
+	}
+	return slots, nil
+}
+
+// writeBackupSlot escribe sb en la ranura slot con la marca de posición, fecha y generación
+func writeBackupSlot(file *os.File, partStart, partSize int32, slot int, sb *Superblock, gen uint16) error {
+	if !hasBackupArea(*sb, partStart, partSize) {
+		return fmt.Errorf("la partición no tiene espacio reservado para copias del superbloque")
+	}
+	var encoded bytes.Buffer
+	if err := binary.Write(&encoded, binary.LittleEndian, sb); err != nil {
+		return err
+	}
+	data := make([]byte, sbBackupSlot)
+	copy(data, encoded.Bytes()[:superblockDiskSize(sb, partStart)])
+	trailer := sbBackupTrailer()
+	binary.LittleEndian.PutUint32(data[trailer:], uint32(partStart))
+	binary.LittleEndian.PutUint32(data[trailer+4:], uint32(time.Now().Unix()))
+	binary.LittleEndian.PutUint16(data[trailer+8:], gen)
+	if _, err := file.WriteAt(data, sbBackupOffset(partStart, partSize, slot)); err != nil {
+		return fmt.Errorf("error al escribir copia %d del superbloque: %v", slot, err)
+	}
+	return nil
+}
+
+// readBackupSuperblocks lee las ranuras de respaldo y devuelve las copias válidas
+func readBackupSuperblocks(file *os.File, partStart, partSize int32) []sbBackup {
+	var backups []sbBackup
+	for k := 1; k <= sbBackupCount; k++ {
+		offset := sbBackupOffset(partStart, partSize, k)
+		if offset < int64(partStart) {
+			break
+		}
+		slot := make([]byte, sbBackupSlot)
+		if _, err := file.ReadAt(slot, offset); err != nil {
+			continue
+		}
+		var sb Superblock
+		binary.Read(bytes.NewReader(slot), binary.LittleEndian, &sb)
+		if sb.SMagic != 0xEF53 {
+			continue
+		}
+
+		// Igual que en readSuperblock, los campos que no existían en la versión se leen como cero
+		trailer := sbBackupTrailer()
+		written := int32(binary.LittleEndian.Uint32(slot[trailer:]))
+		stamp := int64(binary.LittleEndian.Uint32(slot[trailer+4:]))
+		gen := binary.LittleEndian.Uint16(slot[trailer+8:])
+		if n := superblockDiskSize(&sb, written); n > 0 && n < binary.Size(Superblock{}) {
+			clear(slot[n:])
+			binary.Read(bytes.NewReader(slot), binary.LittleEndian, &sb)
+		}
+
+		// Trasladar los punteros absolutos si la partición se movió desde que se escribió la copia
+		delta := partStart - written
+		sb.SBmInodeStart += delta
+		sb.SBmBlockStart += delta
+		sb.SInodeStart += delta
+		sb.SBlockStart += delta
+		if !validBackupSuperblock(&sb, partStart, partSize) {
+			continue
+		}
+		backups = append(backups, sbBackup{Slot: k, SB: sb, Written: time.Unix(stamp, 0), Gen: gen})
+	}
+	return backups
+}
+
+// newestBackup devuelve la copia válida escrita más recientemente
+func newestBackup(backups []sbBackup) (sbBackup, bool) {
+	if len(backups) == 0 {
+		return sbBackup{}, false
+	}
+	newest := backups[0]
+	for _, b := range backups[1:] {
+		if b.newerThan(newest) {
+			newest = b
+		}
+	}
+	return newest, true
+}
+
+// validBackupSuperblock comprueba que la geometría de una copia sea coherente con la partición
+func validBackupSuperblock(sb *Superblock, partStart, partSize int32) bool {
+	if sb.SInodesCount < 2 || sb.SBlocksCount < 2 || sb.SBlockSize <= 0 ||
+		sb.SInodeSize <= 0 || sb.SInodeSize > int32(binary.Size(Inode{})) {
+		return false
+	}
+	if n := superblockDiskSize(sb, partStart); n <= 0 || n > binary.Size(Superblock{}) {
+		return false
+	}
+	if sb.SBmBlockStart != sb.SBmInodeStart+bitmapBytes(sb.SInodesCount, sb.SVersion) ||
+		sb.SInodeStart != sb.SBmBlockStart+bitmapBytes(sb.SBlocksCount, sb.SVersion) ||
+		sb.SBlockStart != sb.SInodeStart+sb.SInodesCount*sb.SInodeSize {
+		return false
+	}
+	return hasBackupArea(*sb, partStart, partSize)
+}
+
+// RESTORESB: Restaura el superbloque principal de una partición desde una copia de respaldo.
+func restoresb(params map[string]string) string {
+	id, hasID := params["id"]
+	if !hasID {
+		return "Error: Parámetro -id es obligatorio"
+	}
+
+	slot := 0
+	if value, ok := params["backup"]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 || n > sbBackupCount {
+			return fmt.Sprintf("Error: Valor de -backup no válido: %s (use 1 a %d)", value, sbBackupCount)
+		}
+		slot = n
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	partStart, partSize, err := getPartitionBounds(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	backups := readBackupSuperblocks(file, partStart, partSize)
+	var chosen sbBackup
+	found := false
+	if slot == 0 {
+		chosen, found = newestBackup(backups)
+	} else {
+		for _, b := range backups {
+			if b.Slot == slot {
+				chosen, found = b, true
+				break
+			}
+		}
+	}
+	if !found {
+		if slot != 0 {
+			return fmt.Sprintf("Error: La copia %d del superbloque de %s no es válida", slot, id)
+		}
+		return fmt.Sprintf("Error: La partición %s no tiene copias válidas del superbloque", id)
+	}
+
+	if err := writeSuperblock(file, partStart, &chosen.SB); err != nil {
+		return fmt.Sprintf("Error al escribir superbloque: %v", err)
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+
+	var salida strings.Builder
+	salida.WriteString(fmt.Sprintf("Superbloque de %s restaurado desde la copia %d (%s)", id, chosen.Slot, chosen.Written.Format("2006-01-02 15:04:05")))
+	salida.WriteString(fmt.Sprintf("\nEjecute fsck -id=%s -repair para actualizar los contadores de inodos y bloques libres", id))
+	return salida.String()
+}
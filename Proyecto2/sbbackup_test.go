@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/binary"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// La marca de posición, fecha y generación debe caber en la ranura sin pisar el superbloque
+func TestBackupTrailerFitsInSlot(t *testing.T) {
+	if end := sbBackupTrailer() + 10; end > sbBackupSlot {
+		t.Fatalf("la marca termina en el byte %d, la ranura tiene %d", end, sbBackupSlot)
+	}
+}
+
+const testPartStart, testPartSize = 1024, 64 * 1024
+
+// newTestDisk crea un disco con una partición primaria P1 y devuelve su superbloque sin escribir
+func newTestDisk(t *testing.T) (*os.File, Superblock) {
+	t.Helper()
+	file, err := os.Create(filepath.Join(t.TempDir(), "disco.mia"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { file.Close() })
+	if err := file.Truncate(testPartStart + testPartSize); err != nil {
+		t.Fatal(err)
+	}
+
+	mbr := MBR{MbrTamano: testPartStart + testPartSize}
+	mbr.MbrPartitions[0] = Partition{PartStatus: '1', PartType: 'P', PartStart: testPartStart, PartSize: testPartSize}
+	copy(mbr.MbrPartitions[0].PartName[:], "P1")
+	if err := writeMBR(file, &mbr); err != nil {
+		t.Fatal(err)
+	}
+
+	sb, err := fsLayout(testPartStart, testPartSize, defaultGeometry)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return file, sb
+}
+
+// Escribe el superbloque y sus copias, daña el principal y comprueba que la copia
+// leída coincida campo por campo con el original
+func TestBackupSuperblockRoundTrip(t *testing.T) {
+	const partStart, partSize = testPartStart, testPartSize
+	file, sb := newTestDisk(t)
+	// Valores distintos de cero en todos los campos, en especial el último (SAllocFit)
+	copy(sb.SMtime[:], "2026-01-02 03:04:05")
+	copy(sb.SUmtime[:], "2026-06-07 08:09:10")
+	sb.SMntCount = 7
+	sb.SFirstIno = 3
+	sb.SFirstBlo = 5
+	sb.SJournalStart = 11
+	sb.SJournalSize = 13
+	sb.SReservedBlocks = 17
+	sb.SAllocFit = allocNextFit
+	if err := writeSuperblock(file, partStart, &sb); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeBackupSuperblocks(file, partStart, partSize, &sb); err != nil {
+		t.Fatal(err)
+	}
+
+	// Dañar el superbloque principal
+	if _, err := file.WriteAt(make([]byte, binary.Size(Superblock{})), partStart); err != nil {
+		t.Fatal(err)
+	}
+
+	got, backup, err := loadSuperblock(file, &MountedPartition{Name: "P1"})
+	if err != nil {
+		t.Fatalf("loadSuperblock: %v", err)
+	}
+	if backup == nil {
+		t.Fatal("se esperaba que se usara una copia de respaldo")
+	}
+	if backup.Written.IsZero() || backup.Written.Unix() == 0 {
+		t.Errorf("la copia no conserva su fecha de escritura: %v", backup.Written)
+	}
+
+	want := reflect.ValueOf(sb)
+	have := reflect.ValueOf(got)
+	for i := 0; i < want.NumField(); i++ {
+		if !reflect.DeepEqual(want.Field(i).Interface(), have.Field(i).Interface()) {
+			t.Errorf("%s: se escribió %v y se leyó %v", want.Type().Field(i).Name, want.Field(i).Interface(), have.Field(i).Interface())
+		}
+	}
+}
+
+// Cada actualización reescribe solo la copia más antigua, así la otra conserva la anterior
+func TestUpdateBackupKeepsPreviousCopy(t *testing.T) {
+	file, sb := newTestDisk(t)
+	sb.SMntCount = 1
+	if err := writeBackupSuperblocks(file, testPartStart, testPartSize, &sb); err != nil {
+		t.Fatal(err)
+	}
+
+	counts := func() map[int]int32 {
+		got := map[int]int32{}
+		for _, b := range readBackupSuperblocks(file, testPartStart, testPartSize) {
+			got[b.Slot] = b.SB.SMntCount
+		}
+		return got
+	}
+	for _, want := range []struct {
+		mounts int32
+		slot   int
+		copies map[int]int32
+	}{
+		{2, 1, map[int]int32{1: 2, 2: 1}},
+		{3, 2, map[int]int32{1: 2, 2: 3}},
+		{4, 1, map[int]int32{1: 4, 2: 3}},
+	} {
+		sb.SMntCount = want.mounts
+		slots, err := updateBackupSuperblock(file, testPartStart, testPartSize, &sb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(slots) != 1 || slots[0] != want.slot {
+			t.Fatalf("montaje %d: se escribieron las ranuras %v, se esperaba %d", want.mounts, slots, want.slot)
+		}
+		if got := counts(); !reflect.DeepEqual(got, want.copies) {
+			t.Fatalf("montaje %d: copias %v, se esperaba %v", want.mounts, got, want.copies)
+		}
+		if newest, _ := newestBackup(readBackupSuperblocks(file, testPartStart, testPartSize)); newest.SB.SMntCount != want.mounts {
+			t.Fatalf("montaje %d: la copia más reciente tiene %d", want.mounts, newest.SB.SMntCount)
+		}
+	}
+}
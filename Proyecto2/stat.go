@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// inodeTypeName describe el tipo de un inodo para mostrarlo al usuario
+func inodeTypeName(itype byte) string {
+	switch itype {
+	case '0':
+		return "carpeta"
+	case '1':
+		return "archivo"
+	case '2':
+		return "enlace simbólico"
+	}
+	return fmt.Sprintf("desconocido (%q)", itype)
+}
+
+// permString muestra los permisos de un inodo al estilo de ls -l, p. ej. drwxrw-r--
+func permString(inode Inode) string {
+	kind := "-"
+	switch inode.IType {
+	case '0':
+		kind = "d"
+	case '2':
+		kind = "l"
+	}
+	return kind + hostFileMode(inode.IPerm).String()[1:]
+}
+
+// STAT: Muestra la información del inodo de un archivo, carpeta o enlace.
+func stat(params map[string]string) string {
+	path, hasPath := params["path"]
+	id, hasID := params["id"]
+	if !hasPath || !hasID {
+		return "Error: Parámetros -id y -path son obligatorios"
+	}
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	// Como stat de POSIX, un enlace simbólico se describe a sí mismo y no a su destino
+	pathParts, err := splitPath(path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	index, err := resolvePath(file, sb, pathParts, false)
+	if err != nil {
+		return fmt.Sprintf("Error: %s no encontrado: %v", path, err)
+	}
+	inode, err := readInode(file, sb, index)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", index, err)
+	}
+
+	usersContent, err := readUsersTxt(file, sb)
+	if err != nil {
+		return fmt.Sprintf("Error al leer users.txt: %v", err)
+	}
+	users, groups := userAndGroupNames(usersContent)
+	owner, ok := users[inode.IUid]
+	if !ok {
+		owner = "(desconocido)"
+	}
+	group, ok := groups[inode.IGid]
+	if !ok {
+		group = "(desconocido)"
+	}
+
+	var salida strings.Builder
+	salida.WriteString(fmt.Sprintf("Ruta: /%s\n", strings.Join(pathParts, "/")))
+	salida.WriteString(fmt.Sprintf("Inodo: %d\n", index))
+	salida.WriteString(fmt.Sprintf("Tipo: %s\n", inodeTypeName(inode.IType)))
+	salida.WriteString(fmt.Sprintf("Tamaño: %d bytes\n", inode.ISize))
+	salida.WriteString(fmt.Sprintf("Enlaces: %d\n", linkCount(inode)))
+	salida.WriteString(fmt.Sprintf("Dueño: %s (uid %d)\n", owner, inode.IUid))
+	salida.WriteString(fmt.Sprintf("Grupo: %s (gid %d)\n", group, inode.IGid))
+	salida.WriteString(fmt.Sprintf("Permisos: 0%03d (%s)\n", inode.IPerm, permString(inode)))
+	if inode.IType == '2' {
+		if target, err := readSymlinkTarget(file, sb, inode); err == nil {
+			salida.WriteString(fmt.Sprintf("Destino: %s\n", target))
+		}
+	}
+	salida.WriteString(fmt.Sprintf("Último acceso: %s\n", strings.Trim(string(inode.IAtime[:]), "\x00")))
+	salida.WriteString(fmt.Sprintf("Creación: %s\n", strings.Trim(string(inode.ICtime[:]), "\x00")))
+	salida.WriteString(fmt.Sprintf("Modificación: %s\n", strings.Trim(string(inode.IMtime[:]), "\x00")))
+
+	// Los 15 punteros de IBlock apuntan directamente a bloques de datos en este formato;
+	// las posiciones 12 a 14 son las que EXT2 reserva para los indirectos simple, doble y triple
+	var direct, indirect []string
+	for k, blockIndex := range inode.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		entry := fmt.Sprintf("[%d]=%d", k, blockIndex)
+		if k < 12 {
+			direct = append(direct, entry)
+		} else {
+			indirect = append(indirect, entry)
+		}
+	}
+	salida.WriteString(fmt.Sprintf("Bloques usados: %d de %d\n", len(direct)+len(indirect), len(inode.IBlock)))
+	salida.WriteString(fmt.Sprintf("Bloques directos (IBlock[0..11]): %s\n", blockListOrNone(direct)))
+	salida.WriteString(fmt.Sprintf("Bloques IBlock[12..14]: %s", blockListOrNone(indirect)))
+	return salida.String()
+}
+
+// blockListOrNone une una lista de bloques o indica que está vacía
+func blockListOrNone(list []string) string {
+	if len(list) == 0 {
+		return "ninguno"
+	}
+	return strings.Join(list, " ")
+}
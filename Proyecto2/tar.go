@@ -0,0 +1,506 @@
+package main
+
+import (
+	"archive/tar"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+// TAR: Guarda un archivo o carpeta de la partición en un archivo tar POSIX del anfitrión.
+// Igual que export, no sobrescribe un archivo existente salvo con -force.
+func tarCmd(params map[string]string) string {
+	diskPath, hasPath := params["path"]
+	id, hasID := params["id"]
+	out, hasOut := params["out"]
+	if !hasPath || !hasID || !hasOut {
+		return "Error: Parámetros -id, -path y -out son obligatorios"
+	}
+	_, force := params["force"]
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	hostPath, err := resolveHostPath(out)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	// Los nombres dentro del tar empiezan en el último componente de -path; la raíz no agrega prefijo
+	pathParts, err := splitPath(diskPath)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	base := ""
+	if len(pathParts) > 0 {
+		base = pathParts[len(pathParts)-1]
+	}
+	rootIndex, err := resolvePath(file, sb, pathParts, true)
+	if err != nil {
+		return fmt.Sprintf("Error: %s no encontrado: %v", diskPath, err)
+	}
+	rootInode, err := readInode(file, sb, rootIndex)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", rootIndex, err)
+	}
+	if !hasReadPermission(rootInode, currentSession) {
+		return fmt.Sprintf("Error: Permiso denegado para leer %s", diskPath)
+	}
+
+	var entries []exportEntry
+	var skipped []string
+	if err := collectExport(file, sb, rootIndex, rootInode, base, strings.TrimSuffix("/"+strings.Join(pathParts, "/"), "/"), &entries, &skipped, map[int32]bool{}); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	usersContent, err := readUsersTxt(file, sb)
+	if err != nil {
+		return fmt.Sprintf("Error al leer users.txt: %v", err)
+	}
+	users, groups := userAndGroupNames(usersContent)
+
+	// Igual que import, nunca se escribe sobre el disco de una partición montada
+	if existing, err := os.Stat(hostPath); err == nil {
+		for _, p := range mountedPartitions {
+			if disk, err := os.Stat(p.Path); err == nil && os.SameFile(existing, disk) {
+				return fmt.Sprintf("Error: %s es el disco de la partición montada %s", hostPath, p.ID)
+			}
+		}
+	}
+
+	// Con -force se quita lo que haya para no escribir a través de un enlace existente
+	if existing, err := os.Lstat(hostPath); err == nil && force {
+		if existing.IsDir() {
+			return fmt.Sprintf("Error: %s es una carpeta", hostPath)
+		}
+		if err := os.Remove(hostPath); err != nil {
+			return fmt.Sprintf("Error al reemplazar %s: %v", hostPath, err)
+		}
+	}
+	outFile, err := os.OpenFile(hostPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return fmt.Sprintf("Error: Ya existe %s en el anfitrión (use -force para sobrescribir)", hostPath)
+	}
+	if err != nil {
+		return fmt.Sprintf("Error al crear %s: %v", hostPath, err)
+	}
+	defer outFile.Close()
+	tw := tar.NewWriter(outFile)
+
+	var files, dirs, links int
+	var bytesWritten int64
+	written := map[int32]string{} // Inodo -> nombre ya guardado, para representar enlaces duros
+	for _, e := range entries {
+		if e.hostPath == "" {
+			continue // La raíz de la partición no tiene nombre dentro del tar
+		}
+		hdr := &tar.Header{
+			Name:   e.hostPath,
+			Mode:   int64(hostFileMode(e.inode.IPerm)),
+			Uid:    int(e.inode.IUid),
+			Gid:    int(e.inode.IGid),
+			Uname:  users[e.inode.IUid],
+			Gname:  groups[e.inode.IGid],
+			Format: tar.FormatPAX,
+		}
+		if mtime, ok := inodeTime(e.inode.IMtime); ok {
+			hdr.ModTime = mtime
+		}
+
+		var content []byte
+		switch e.inode.IType {
+		case '0':
+			hdr.Typeflag = tar.TypeDir
+			hdr.Name += "/"
+			dirs++
+		case '2':
+			target, err := readSymlinkTarget(file, sb, e.inode)
+			if err != nil {
+				return fmt.Sprintf("Error al leer %s: %v", e.hostPath, err)
+			}
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = target
+			links++
+		default:
+			if first, ok := written[e.index]; ok {
+				hdr.Typeflag = tar.TypeLink
+				hdr.Linkname = first
+				links++
+				break
+			}
+			if content, err = readFileContent(file, sb, e.inode); err != nil {
+				return fmt.Sprintf("Error al leer %s: %v", e.hostPath, err)
+			}
+			hdr.Typeflag = tar.TypeReg
+			hdr.Size = int64(len(content))
+			written[e.index] = e.hostPath
+			files++
+			bytesWritten += hdr.Size
+		}
+
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Sprintf("Error al escribir %s en el tar: %v", e.hostPath, err)
+		}
+		if _, err := tw.Write(content); err != nil {
+			return fmt.Sprintf("Error al escribir %s en el tar: %v", e.hostPath, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Sprintf("Error al cerrar el tar: %v", err)
+	}
+
+	var salida strings.Builder
+	salida.WriteString(fmt.Sprintf("Archivo %s creado desde %s: %d archivo(s), %d carpeta(s), %d enlace(s), %d bytes", hostPath, diskPath, files, dirs, links, bytesWritten))
+	for _, s := range skipped {
+		salida.WriteString(fmt.Sprintf("\nOmitido %s: permiso de lectura denegado", s))
+	}
+	return salida.String()
+}
+
+// untarDir es una carpeta creada por untar cuyos permisos y fecha se aplican al terminar
+type untarDir struct {
+	index int32
+	hdr   *tar.Header
+}
+
+// UNTAR: Extrae un archivo tar del anfitrión dentro de una carpeta de la partición.
+func untar(params map[string]string) string {
+	in, hasIn := params["in"]
+	id, hasID := params["id"]
+	dest, hasDest := params["dest"]
+	if !hasIn || !hasID || !hasDest {
+		return "Error: Parámetros -id, -in y -dest son obligatorios"
+	}
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	hostPath, err := resolveHostPath(in)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	inFile, err := os.Open(hostPath)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir %s: %v", hostPath, err)
+	}
+	defer inFile.Close()
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	destParts, err := splitPath(dest)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	destIndex, err := resolvePath(file, sb, destParts, true)
+	if err != nil {
+		return fmt.Sprintf("Error: %s no encontrado: %v", dest, err)
+	}
+	destInode, err := readInode(file, sb, destIndex)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", destIndex, err)
+	}
+	if destInode.IType != '0' {
+		return fmt.Sprintf("Error: %s no es una carpeta", dest)
+	}
+	if !hasWritePermission(destInode, currentSession.UserID, currentSession.GroupID) {
+		return fmt.Sprintf("Error: Permisos insuficientes para escribir en %s", dest)
+	}
+
+	// Solo root conserva los dueños del tar; los demás usuarios quedan como dueños de todo
+	var uids, gids map[string]int32
+	keepOwners := currentSession.Username == "root"
+	if keepOwners {
+		usersContent, err := readUsersTxt(file, sb)
+		if err != nil {
+			return fmt.Sprintf("Error al leer users.txt: %v", err)
+		}
+		users, groups := userAndGroupNames(usersContent)
+		uids, gids = map[string]int32{}, map[string]int32{}
+		for uid, name := range users {
+			uids[name] = uid
+		}
+		for gid, name := range groups {
+			gids[name] = gid
+		}
+	}
+
+	im := &importer{file: file, mp: mp, sb: sb}
+	freeInodes, freeBlocks := sb.SFreeInodesCount, sb.SFreeBlocksCount
+	created := map[string]int32{"": destIndex} // Ruta dentro del tar -> inodo creado
+	var dirs []untarDir
+
+	tr := tar.NewReader(inFile)
+	var untarErr error
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			untarErr = fmt.Errorf("tar inválido: %v", err)
+			break
+		}
+
+		name := cleanTarName(hdr.Name)
+		if name == "" {
+			continue
+		}
+		parentIndex, err := im.untarParent(created, name)
+		if err != nil {
+			untarErr = err
+			break
+		}
+		base := path.Base(name)
+		if len(base) > 12 {
+			im.skipped = append(im.skipped, fmt.Sprintf("%s: el nombre excede 12 caracteres", hdr.Name))
+			continue
+		}
+		existing, err := lookupEntry(file, im.sb, parentIndex, base)
+		if err != nil {
+			untarErr = err
+			break
+		}
+
+		perm := inodePerm(os.FileMode(hdr.Mode))
+		var index int32
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if existing != -1 {
+				if inode, err := readInode(file, im.sb, existing); err == nil && inode.IType == '0' {
+					created[name] = existing
+					continue
+				}
+				im.skipped = append(im.skipped, fmt.Sprintf("%s: ya existe", hdr.Name))
+				continue
+			}
+			// Se crea con permisos abiertos para poder llenarla; los del tar se aplican al final
+			index, err = im.add(parentIndex, base, func(alloc *Allocator) (int32, error) {
+				return writeNewFolder(file, im.sb, alloc, parentIndex, 775)
+			})
+			if err == nil {
+				im.dirs++
+				created[name] = index
+				dirs = append(dirs, untarDir{index: index, hdr: hdr})
+			}
+
+		case tar.TypeReg, tar.TypeSymlink, tar.TypeLink:
+			if existing != -1 {
+				im.skipped = append(im.skipped, fmt.Sprintf("%s: ya existe", hdr.Name))
+				continue
+			}
+			switch hdr.Typeflag {
+			case tar.TypeLink:
+				index, err = im.untarHardLink(created, hdr, parentIndex, base)
+				if err == nil {
+					im.links++
+				}
+			case tar.TypeSymlink:
+				index, err = im.add(parentIndex, base, func(alloc *Allocator) (int32, error) {
+					return writeNewInode(file, im.sb, alloc, '2', 777, []byte(hdr.Linkname))
+				})
+				if err == nil {
+					im.links++
+				}
+			default:
+				maxSize := int64(len(Inode{}.IBlock)) * int64(im.sb.SBlockSize)
+				if hdr.Size > maxSize {
+					im.skipped = append(im.skipped, fmt.Sprintf("%s: %d bytes excede el máximo de %d por archivo", hdr.Name, hdr.Size, maxSize))
+					continue
+				}
+				content, readErr := io.ReadAll(tr)
+				if readErr != nil {
+					untarErr = fmt.Errorf("tar inválido en %s: %v", hdr.Name, readErr)
+					break
+				}
+				index, err = im.add(parentIndex, base, func(alloc *Allocator) (int32, error) {
+					return writeNewInode(file, im.sb, alloc, '1', perm, content)
+				})
+				if err == nil {
+					im.files++
+					im.bytes += int64(len(content))
+				}
+			}
+			if untarErr == nil && err == nil {
+				created[name] = index
+				if hdr.Typeflag != tar.TypeLink {
+					err = im.applyTarHeader(index, hdr, -1, keepOwners, uids, gids)
+				}
+			}
+
+		default:
+			im.skipped = append(im.skipped, fmt.Sprintf("%s: tipo de entrada no soportado", hdr.Name))
+			continue
+		}
+		if untarErr != nil {
+			break
+		}
+		if err != nil {
+			untarErr = fmt.Errorf("%s: %v", hdr.Name, err)
+			break
+		}
+	}
+
+	// Permisos, dueños y fechas de las carpetas, de las más profundas a las de arriba
+	for i := len(dirs) - 1; i >= 0; i-- {
+		d := dirs[i]
+		if err := im.applyTarHeader(d.index, d.hdr, inodePerm(os.FileMode(d.hdr.Mode)), keepOwners, uids, gids); err != nil && untarErr == nil {
+			untarErr = fmt.Errorf("%s: %v", d.hdr.Name, err)
+		}
+	}
+	if err := file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+
+	var salida strings.Builder
+	if untarErr != nil {
+		salida.WriteString(fmt.Sprintf("Error: Extracción detenida: %v\n", untarErr))
+		salida.WriteString("Se conserva lo extraído hasta ese punto\n")
+	}
+	salida.WriteString(fmt.Sprintf("Extraído %s en %s: %d archivo(s), %d carpeta(s), %d enlace(s), %d bytes",
+		hostPath, dest, im.files, im.dirs, im.links, im.bytes))
+	salida.WriteString(fmt.Sprintf("\nInodos usados: %d, bloques usados: %d",
+		freeInodes-im.sb.SFreeInodesCount, freeBlocks-im.sb.SFreeBlocksCount))
+	for _, s := range im.skipped {
+		salida.WriteString("\nOmitido " + s)
+	}
+	return salida.String()
+}
+
+// cleanTarName normaliza el nombre de una entrada del tar a una ruta relativa a la carpeta
+// destino; los / iniciales y los .. que saldrían de ella se descartan, como hace tar
+func cleanTarName(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+// untarParent devuelve la carpeta donde va la entrada name, creando las carpetas
+// intermedias que el tar no declaró
+func (im *importer) untarParent(created map[string]int32, name string) (int32, error) {
+	dir := path.Dir(name)
+	if dir == "." {
+		return created[""], nil
+	}
+	if index, ok := created[dir]; ok {
+		return index, nil
+	}
+	parentIndex, err := im.untarParent(created, dir)
+	if err != nil {
+		return -1, err
+	}
+	base := path.Base(dir)
+	if len(base) > 12 {
+		return -1, fmt.Errorf("el nombre %s excede 12 caracteres", base)
+	}
+	index, err := lookupEntry(im.file, im.sb, parentIndex, base)
+	if err != nil {
+		return -1, err
+	}
+	if index == -1 {
+		// Mismo permiso que las carpetas explícitas del tar, que no llegan a reemplazarse
+		if index, err = im.add(parentIndex, base, func(alloc *Allocator) (int32, error) {
+			return writeNewFolder(im.file, im.sb, alloc, parentIndex, 775)
+		}); err != nil {
+			return -1, fmt.Errorf("%s: %v", dir, err)
+		}
+		im.dirs++
+	} else if inode, err := readInode(im.file, im.sb, index); err != nil || inode.IType != '0' {
+		return -1, fmt.Errorf("%s existe y no es una carpeta", dir)
+	}
+	created[dir] = index
+	return index, nil
+}
+
+// untarHardLink enlaza base en parentIndex con un archivo extraído antes en este mismo tar
+func (im *importer) untarHardLink(created map[string]int32, hdr *tar.Header, parentIndex int32, base string) (int32, error) {
+	index, found := created[cleanTarName(hdr.Linkname)]
+	if !found {
+		return -1, fmt.Errorf("el destino %s del enlace duro no está en el tar", hdr.Linkname)
+	}
+	if !hasLinkCounts(im.sb) {
+		return -1, fmt.Errorf("el formato de esta partición no guarda contadores de enlaces")
+	}
+	inode, err := readInode(im.file, im.sb, index)
+	if err != nil {
+		return -1, err
+	}
+	if inode.IType == '0' {
+		return -1, fmt.Errorf("no se permiten enlaces duros a carpetas (%s)", hdr.Linkname)
+	}
+	if _, err := im.add(parentIndex, base, func(alloc *Allocator) (int32, error) { return index, nil }); err != nil {
+		return -1, err
+	}
+	inode.ILinks = linkCount(inode) + 1
+	return index, writeInode(im.file, im.sb, index, &inode)
+}
+
+// applyTarHeader copia al inodo la fecha de modificación del tar y, si keepOwners,
+// su dueño y grupo (por nombre cuando existe en users.txt). perm >= 0 también reemplaza IPerm.
+func (im *importer) applyTarHeader(index int32, hdr *tar.Header, perm int32, keepOwners bool, uids, gids map[string]int32) error {
+	inode, err := readInode(im.file, im.sb, index)
+	if err != nil {
+		return err
+	}
+	if perm >= 0 {
+		inode.IPerm = perm
+	}
+	if keepOwners {
+		inode.IUid, inode.IGid = int32(hdr.Uid), int32(hdr.Gid)
+		if uid, ok := uids[hdr.Uname]; ok {
+			inode.IUid = uid
+		}
+		if gid, ok := gids[hdr.Gname]; ok {
+			inode.IGid = gid
+		}
+	}
+	if !hdr.ModTime.IsZero() {
+		inode.IMtime = [19]byte{}
+		copy(inode.IMtime[:], hdr.ModTime.In(time.Local).Format("2006-01-02 15:04:05"))
+	}
+	return writeInode(im.file, im.sb, index, &inode)
+}
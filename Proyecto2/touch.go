@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// TOUCH: Actualiza las fechas de acceso y modificación de un archivo o carpeta, o crea
+// un archivo vacío si no existe. Con -date se usa esa fecha en lugar de la actual.
+func touch(params map[string]string) string {
+	path, hasPath := params["path"]
+	id, hasID := params["id"]
+	if !hasPath || !hasID {
+		return "Error: Parámetros -id y -path son obligatorios"
+	}
+
+	// Fecha a aplicar: "2006-01-02" o "2006-01-02 15:04:05"
+	fecha := timestamp()
+	if dateStr, hasDate := params["date"]; hasDate {
+		dateStr = strings.Trim(dateStr, "\"")
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", dateStr, time.Local)
+		if err != nil {
+			t, err = time.ParseInLocation("2006-01-02", dateStr, time.Local)
+		}
+		if err != nil {
+			return fmt.Sprintf("Error: Fecha inválida %s (use AAAA-MM-DD o \"AAAA-MM-DD HH:MM:SS\")", dateStr)
+		}
+		copy(fecha[:], t.Format("2006-01-02 15:04:05"))
+	}
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	pathParts, err := splitPath(path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	index, err := resolvePath(file, sb, pathParts, true)
+	if err != nil {
+		// Si solo falta el último elemento se crea como archivo vacío, igual que touch
+		if len(pathParts) == 0 {
+			return fmt.Sprintf("Error: %s no encontrado: %v", path, err)
+		}
+		if _, parentErr := resolvePath(file, sb, pathParts[:len(pathParts)-1], true); parentErr != nil {
+			return fmt.Sprintf("Error: %s no encontrado: %v", path, err)
+		}
+		if err = createFile(file, mp, path, nil); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if index, err = resolvePath(file, sb, pathParts, true); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+		if _, hasDate := params["date"]; !hasDate {
+			return fmt.Sprintf("Archivo %s creado exitosamente", path)
+		}
+	}
+
+	inode, err := readInode(file, sb, index)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo %d: %v", index, err)
+	}
+	if !hasWritePermission(inode, currentSession.UserID, currentSession.GroupID) {
+		return fmt.Sprintf("Error: Permiso denegado para modificar %s", path)
+	}
+	inode.IAtime, inode.IMtime = fecha, fecha
+	if err = writeInode(file, sb, index, &inode); err != nil {
+		return fmt.Sprintf("Error al escribir inodo %d: %v", index, err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+	return fmt.Sprintf("Fechas de %s actualizadas a %s", path, strings.Trim(string(fecha[:]), "\x00"))
+}
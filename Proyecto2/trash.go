@@ -0,0 +1,511 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// La papelera es la carpeta /.trash de cada partición. Cada elemento eliminado queda en una
+// subcarpeta numerada junto con el archivo .origen, que guarda de dónde salió:
+//
+//	/.trash/3/.origen    ruta=/docs/a.txt, fecha=..., uid=...
+//	/.trash/3/a.txt      el elemento eliminado, con su nombre original
+//
+// /.trash y sus subcarpetas tienen permiso 700, así que ls, find, du, tar, export y copy
+// no muestran a un usuario lo que eliminaron otros.
+const (
+	trashFolder   = ".trash"
+	trashInfoName = ".origen"
+)
+
+// trashItem describe un elemento de la papelera
+type trashItem struct {
+	slot      string // Nombre de la subcarpeta dentro de /.trash
+	slotIndex int32
+	name      string // Nombre original del elemento
+	index     int32  // Inodo del elemento
+	path      string // Ruta original
+	date      string
+	uid       int32
+}
+
+// canManage indica si la sesión puede restaurar o vaciar el elemento: root o quien lo eliminó
+func (t trashItem) canManage() bool {
+	return currentSession.Username == "root" || t.uid == currentSession.UserID
+}
+
+// moveToTrash mueve la entrada name (inodo index) de la carpeta parentIndex a la papelera
+func moveToTrash(file *os.File, mp *MountedPartition, sb Superblock, parentIndex int32, name string, index int32, pathParts []string) string {
+	alloc, err := newAllocator(file, mp, &sb)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	slot, err := trashElement(file, sb, alloc, parentIndex, name, index, "/"+strings.Join(pathParts, "/"))
+	if err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: No se pudo mover %s a la papelera: %v (use -permanent para eliminarlo definitivamente)", name, err)
+	}
+	if err = alloc.Commit(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+	return fmt.Sprintf("%s movido a la papelera (restore -name=%s para recuperarlo)", name, slot)
+}
+
+// trashElement crea la subcarpeta de la papelera con su .origen y mueve allí el elemento.
+// Devuelve el nombre de la subcarpeta. La entrada original se quita solo cuando la papelera
+// ya quedó completa; si algo falla se deshacen las entradas escritas en carpetas existentes,
+// porque Rollback solo revierte los bitmaps.
+func trashElement(file *os.File, sb Superblock, alloc *Allocator, parentIndex int32, name string, index int32, path string) (slot string, err error) {
+	if name == trashInfoName {
+		return "", fmt.Errorf("el nombre %s está reservado por la papelera", trashInfoName)
+	}
+
+	var undo []func()
+	defer func() {
+		if err != nil {
+			for k := len(undo) - 1; k >= 0; k-- {
+				undo[k]()
+			}
+		}
+	}()
+	// link agrega la entrada y registra cómo quitarla. También se guarda el inodo de la carpeta
+	// por si addFolderEntry le asignó un bloque nuevo.
+	link := func(folderIndex int32, entry string, child int32) error {
+		saved, err := readInode(file, sb, folderIndex)
+		if err != nil {
+			return fmt.Errorf("error al leer inodo %d: %v", folderIndex, err)
+		}
+		undo = append(undo, func() {
+			removeFolderEntry(file, sb, folderIndex, entry)
+			writeInode(file, sb, folderIndex, &saved)
+		})
+		return addFolderEntry(file, sb, alloc, folderIndex, entry, child)
+	}
+
+	trashIndex, err := lookupEntry(file, sb, 0, trashFolder)
+	if err != nil {
+		return "", err
+	}
+	if trashIndex == -1 {
+		// La papelera pertenece al dueño de la raíz y solo él puede listarla; cada usuario ve
+		// sus elementos con trash -list
+		root, err := readInode(file, sb, 0)
+		if err != nil {
+			return "", fmt.Errorf("error al leer inodo 0: %v", err)
+		}
+		if trashIndex, err = writeNewFolder(file, sb, alloc, 0, 700); err != nil {
+			return "", err
+		}
+		folder, err := readInode(file, sb, trashIndex)
+		if err != nil {
+			return "", fmt.Errorf("error al leer inodo %d: %v", trashIndex, err)
+		}
+		folder.IUid, folder.IGid = root.IUid, root.IGid
+		if err = writeInode(file, sb, trashIndex, &folder); err != nil {
+			return "", fmt.Errorf("error al escribir inodo %d: %v", trashIndex, err)
+		}
+		if err = link(0, trashFolder, trashIndex); err != nil {
+			return "", err
+		}
+	}
+
+	// La subcarpeta toma el siguiente número libre
+	items, err := readTrash(file, sb, trashIndex)
+	if err != nil {
+		return "", err
+	}
+	next := 1
+	for _, item := range items {
+		if n, err := strconv.Atoi(item.slot); err == nil && n >= next {
+			next = n + 1
+		}
+	}
+	slot = strconv.Itoa(next)
+
+	// Solo quien eliminó el elemento (y root) puede entrar a su subcarpeta
+	slotIndex, err := writeNewFolder(file, sb, alloc, trashIndex, 700)
+	if err != nil {
+		return "", err
+	}
+	info := fmt.Sprintf("ruta=%s\nfecha=%s\nuid=%d\n", path, time.Now().Format("2006-01-02 15:04:05"), currentSession.UserID)
+	infoIndex, err := writeNewInode(file, sb, alloc, '1', 600, []byte(info))
+	if err != nil {
+		return "", err
+	}
+	if err = addFolderEntry(file, sb, alloc, slotIndex, trashInfoName, infoIndex); err != nil {
+		return "", err
+	}
+	if err = addFolderEntry(file, sb, alloc, slotIndex, name, index); err != nil {
+		return "", err
+	}
+	if err = link(trashIndex, slot, slotIndex); err != nil {
+		return "", err
+	}
+	undo = append(undo, func() { setParentEntry(file, sb, index, parentIndex) })
+	if err = setParentEntry(file, sb, index, slotIndex); err != nil {
+		return "", err
+	}
+	if err = removeFolderEntry(file, sb, parentIndex, name); err != nil {
+		return "", err
+	}
+	return slot, nil
+}
+
+// readTrash lee los elementos de la papelera en el orden de sus subcarpetas. Las
+// subcarpetas sin .origen válido se ignoran.
+func readTrash(file *os.File, sb Superblock, trashIndex int32) ([]trashItem, error) {
+	trash, err := readInode(file, sb, trashIndex)
+	if err != nil {
+		return nil, fmt.Errorf("error al leer inodo %d: %v", trashIndex, err)
+	}
+	var items []trashItem
+	for _, blockIndex := range trash.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(file, sb, blockIndex)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer bloque %d: %v", blockIndex, err)
+		}
+		for _, content := range folderBlock.BContent {
+			slot := strings.Trim(string(content.BName[:]), "\x00")
+			if slot == "" || slot == "." || slot == ".." || content.BInode == -1 {
+				continue
+			}
+			if item, ok := readTrashItem(file, sb, slot, content.BInode); ok {
+				items = append(items, item)
+			}
+		}
+	}
+	sort.Slice(items, func(a, b int) bool {
+		na, _ := strconv.Atoi(items[a].slot)
+		nb, _ := strconv.Atoi(items[b].slot)
+		return na < nb
+	})
+	return items, nil
+}
+
+// readTrashItem interpreta la subcarpeta slot de la papelera
+func readTrashItem(file *os.File, sb Superblock, slot string, slotIndex int32) (trashItem, bool) {
+	item := trashItem{slot: slot, slotIndex: slotIndex, index: -1}
+	folder, err := readInode(file, sb, slotIndex)
+	if err != nil || folder.IType != '0' {
+		return item, false
+	}
+	infoIndex := int32(-1)
+	for _, blockIndex := range folder.IBlock {
+		if blockIndex == -1 {
+			continue
+		}
+		folderBlock, err := readFolderBlock(file, sb, blockIndex)
+		if err != nil {
+			return item, false
+		}
+		for _, content := range folderBlock.BContent {
+			name := strings.Trim(string(content.BName[:]), "\x00")
+			switch {
+			case name == "" || name == "." || name == ".." || content.BInode == -1:
+			case name == trashInfoName:
+				infoIndex = content.BInode
+			default:
+				item.name, item.index = name, content.BInode
+			}
+		}
+	}
+	if infoIndex == -1 || item.index == -1 {
+		return item, false
+	}
+
+	info, err := readInode(file, sb, infoIndex)
+	if err != nil {
+		return item, false
+	}
+	content, err := readFileContent(file, sb, info)
+	if err != nil {
+		return item, false
+	}
+	for _, line := range strings.Split(string(content), "\n") {
+		key, value, _ := strings.Cut(line, "=")
+		switch key {
+		case "ruta":
+			item.path = value
+		case "fecha":
+			item.date = value
+		case "uid":
+			if uid, err := strconv.Atoi(value); err == nil {
+				item.uid = int32(uid)
+			}
+		}
+	}
+	return item, item.path != ""
+}
+
+// TRASH: Lista la papelera de la partición (-list) o la vacía (-empty), opcionalmente solo
+// los elementos eliminados hace más de -older días.
+func trash(params map[string]string) string {
+	id, hasID := params["id"]
+	if !hasID {
+		return "Error: Parámetro -id es obligatorio"
+	}
+	_, list := params["list"]
+	_, empty := params["empty"]
+	if list == empty {
+		return "Error: Especifique -list o -empty"
+	}
+	olderStr, hasOlder := params["older"]
+	days := 0
+	if hasOlder {
+		if !empty {
+			return "Error: -older solo se usa con -empty"
+		}
+		n, err := strconv.Atoi(olderStr)
+		if err != nil || n < 0 {
+			return fmt.Sprintf("Error: Valor de -older inválido: %s", olderStr)
+		}
+		days = n
+	}
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	trashIndex, err := lookupEntry(file, sb, 0, trashFolder)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	var items []trashItem
+	if trashIndex != -1 {
+		if items, err = readTrash(file, sb, trashIndex); err != nil {
+			return fmt.Sprintf("Error: %v", err)
+		}
+	}
+
+	if list {
+		usersContent, err := readUsersTxt(file, sb)
+		if err != nil {
+			return fmt.Sprintf("Error al leer users.txt: %v", err)
+		}
+		users, _ := userAndGroupNames(usersContent)
+		var salida strings.Builder
+		salida.WriteString(fmt.Sprintf("%-6s %-16s %-19s %-10s %s", "Nombre", "Tipo", "Eliminado", "Usuario", "Ruta original"))
+		shown := 0
+		for _, item := range items {
+			if !item.canManage() {
+				continue
+			}
+			inode, err := readInode(file, sb, item.index)
+			if err != nil {
+				return fmt.Sprintf("Error al leer inodo %d: %v", item.index, err)
+			}
+			user, ok := users[item.uid]
+			if !ok {
+				user = strconv.Itoa(int(item.uid))
+			}
+			salida.WriteString(fmt.Sprintf("\n%-6s %-16s %-19s %-10s %s", item.slot, inodeTypeName(inode.IType), item.date, user, item.path))
+			shown++
+		}
+		if shown == 0 {
+			return "La papelera está vacía"
+		}
+		return salida.String()
+	}
+
+	// Vaciar: se liberan la subcarpeta, su .origen y el elemento con todo su contenido
+	limit := time.Now().AddDate(0, 0, -days)
+	alloc, err := newAllocator(file, mp, &sb)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	freeBefore := alloc.FreeBlocks()
+	removed := 0
+	for _, item := range items {
+		if !item.canManage() {
+			continue
+		}
+		if hasOlder {
+			deleted, err := time.ParseInLocation("2006-01-02 15:04:05", item.date, time.Local)
+			if err != nil || deleted.After(limit) {
+				continue
+			}
+		}
+		if err = freeSubtree(file, sb, alloc, item.slotIndex); err != nil {
+			alloc.Rollback()
+			return fmt.Sprintf("Error al vaciar la papelera: %v", err)
+		}
+		if err = removeFolderEntry(file, sb, trashIndex, item.slot); err != nil {
+			alloc.Rollback()
+			return fmt.Sprintf("Error al vaciar la papelera: %v", err)
+		}
+		removed++
+	}
+	freed := alloc.FreeBlocks() - freeBefore
+	if err = alloc.Commit(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+	if removed == 0 {
+		return "No hay elementos que eliminar de la papelera"
+	}
+	return fmt.Sprintf("%d elemento(s) eliminado(s) definitivamente, %d bloque(s) liberado(s)", removed, freed)
+}
+
+// RESTORE: Devuelve un elemento de la papelera a su ruta original. -name es el número de
+// la papelera o el nombre original del elemento.
+func restore(params map[string]string) string {
+	id, hasID := params["id"]
+	name, hasName := params["name"]
+	if !hasID || !hasName {
+		return "Error: Parámetros -id y -name son obligatorios"
+	}
+
+	if currentSession == nil {
+		return "Error: No hay sesión activa"
+	}
+
+	var mp *MountedPartition
+	for _, p := range mountedPartitions {
+		if p.ID == id {
+			mp = &p
+			break
+		}
+	}
+	if mp == nil {
+		return fmt.Sprintf("Error: Partición %s no encontrada", id)
+	}
+
+	file, err := os.OpenFile(mp.Path, os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Sprintf("Error al abrir disco: %v", err)
+	}
+	defer file.Close()
+
+	sb, err := readSuperblock(file, mp)
+	if err != nil {
+		return fmt.Sprintf("Error al leer superbloque: %v", err)
+	}
+
+	trashIndex, err := lookupEntry(file, sb, 0, trashFolder)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if trashIndex == -1 {
+		return "Error: La papelera está vacía"
+	}
+	items, err := readTrash(file, sb, trashIndex)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+
+	// Buscar por número de papelera y, si no, por nombre original
+	var matches []trashItem
+	for _, item := range items {
+		if item.slot == name {
+			matches = []trashItem{item}
+			break
+		}
+		if item.name == name {
+			matches = append(matches, item)
+		}
+	}
+	if len(matches) == 0 {
+		return fmt.Sprintf("Error: %s no está en la papelera", name)
+	}
+	if len(matches) > 1 {
+		var slots []string
+		for _, item := range matches {
+			slots = append(slots, fmt.Sprintf("%s (%s)", item.slot, item.path))
+		}
+		return fmt.Sprintf("Error: Hay varios elementos llamados %s en la papelera, indique su número: %s", name, strings.Join(slots, ", "))
+	}
+	item := matches[0]
+	if !item.canManage() {
+		return fmt.Sprintf("Error: Solo root o quien eliminó %s puede restaurarlo", item.path)
+	}
+
+	// La carpeta original debe seguir existiendo y no tener otro elemento con ese nombre
+	pathParts, err := normalizePath(item.path)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	parentIndex, err := navigateToParent(file, sb, pathParts[:len(pathParts)-1])
+	if err != nil {
+		return fmt.Sprintf("Error: No se puede restaurar %s: %v", item.path, err)
+	}
+	parentInode, err := readInode(file, sb, parentIndex)
+	if err != nil {
+		return fmt.Sprintf("Error al leer inodo padre: %v", err)
+	}
+	if !hasWritePermission(parentInode, currentSession.UserID, currentSession.GroupID) {
+		return "Error: Permisos insuficientes para escribir en la carpeta original"
+	}
+	originalName := pathParts[len(pathParts)-1]
+	if existing, err := lookupEntry(file, sb, parentIndex, originalName); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	} else if existing != -1 {
+		return fmt.Sprintf("Error: Ya existe %s", item.path)
+	}
+
+	alloc, err := newAllocator(file, mp, &sb)
+	if err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	// Primero se enlaza en la carpeta original, luego se libera la subcarpeta de la papelera
+	if err = addFolderEntry(file, sb, alloc, parentIndex, originalName, item.index); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = setParentEntry(file, sb, item.index, parentIndex); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = removeFolderEntry(file, sb, item.slotIndex, item.name); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = freeSubtree(file, sb, alloc, item.slotIndex); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = removeFolderEntry(file, sb, trashIndex, item.slot); err != nil {
+		alloc.Rollback()
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = alloc.Commit(); err != nil {
+		return fmt.Sprintf("Error: %v", err)
+	}
+	if err = file.Sync(); err != nil {
+		return fmt.Sprintf("Error syncing disk: %v", err)
+	}
+	return fmt.Sprintf("%s restaurado exitosamente", item.path)
+}